@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Position is a location in the source, with lines and columns starting at 1
+// and columns counted in characters
+type Position struct {
+	Offset, Line, Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Span covers the source of a node, End being just after its last character
+type Span struct {
+	Start, End Position
+}
+
+func (s Span) Location() Span {
+	return s
+}
+
+// Node is a value of the typed syntax tree built by parseAST
+type Node interface {
+	Location() Span
+}
+
+type ObjectNode struct {
+	Span
+	Members []*MemberNode
+}
+
+// MemberNode spans from the key to the end of the value. Duplicate keys are
+// all kept, in the order found.
+type MemberNode struct {
+	Span
+	Key   *StringNode
+	Value Node
+}
+
+type ArrayNode struct {
+	Span
+	Elements []Node
+}
+
+type StringNode struct {
+	Span
+	Text string
+}
+
+type NumberNode struct {
+	Span
+	Literal string
+	Value   float64
+}
+
+type BoolNode struct {
+	Span
+	Value bool
+}
+
+type NullNode struct {
+	Span
+}
+
+// lineIndex converts offsets into positions using the offsets where each line
+// starts. The last position is kept to count the columns from it when the
+// offsets come in order, as a long line would make counting from its start
+// quadratic.
+type lineIndex struct {
+	data   string
+	starts []int
+	last   Position
+}
+
+func newLineIndex(data string) *lineIndex {
+	index := &lineIndex{data: data, starts: []int{0}}
+	for i := 0; i < len(data); i++ {
+		if data[i] == '\n' {
+			index.starts = append(index.starts, i+1)
+		}
+	}
+	return index
+}
+
+func (index *lineIndex) position(offset int) Position {
+	line, found := slices.BinarySearch(index.starts, offset)
+	if !found {
+		line--
+	}
+	from, column := index.starts[line], 1
+	if index.last.Line == line+1 && index.last.Offset <= offset {
+		from, column = index.last.Offset, index.last.Column
+	}
+	index.last = Position{offset, line + 1, column + utf8.RuneCountInString(index.data[from:offset])}
+	return index.last
+}
+
+func (index *lineIndex) span(start, end int) Span {
+	return Span{index.position(start), index.position(end)}
+}
+
+func parseAST(data string) (node Node, err error) {
+	tokens, err := tokenize(data)
+	if err != nil {
+		return
+	}
+	if len(tokens) == 0 {
+		return nil, ErrEmpty
+	}
+	node, end, err := astTokens(newLineIndex(data), tokens, 0)
+	if err != nil {
+		return
+	}
+	if end < len(tokens) {
+		return nil, ErrToken
+	}
+	if _, ok := node.(*ObjectNode); payloadOnly && !ok {
+		if _, ok := node.(*ArrayNode); !ok {
+			return nil, ErrPayload
+		}
+	}
+	return
+}
+
+func astTokens(index *lineIndex, tokens []*Token, start int) (node Node, end int, err error) {
+	depth++
+	defer func() {
+		depth--
+	}()
+	if depth > maxDepth {
+		return nil, 0, ErrMaxDepth
+	}
+	if start >= len(tokens) {
+		return nil, 0, ErrToken
+	}
+	token := tokens[start]
+	switch token.Type {
+	case '[':
+		return astArray(index, tokens, start)
+	case '{':
+		return astObject(index, tokens, start)
+	}
+	span := index.span(token.Start, token.End)
+	switch token.Type {
+	case 'S':
+		return &StringNode{span, token.Content}, start + 1, nil
+	case '0':
+		return &NumberNode{span, token.Content, token.Value}, start + 1, nil
+	case 'n':
+		return &NullNode{span}, start + 1, nil
+	case 't':
+		return &BoolNode{span, true}, start + 1, nil
+	case 'f':
+		return &BoolNode{span, false}, start + 1, nil
+	default:
+		return nil, 0, ErrToken
+	}
+}
+
+func astArray(index *lineIndex, tokens []*Token, start int) (node Node, end int, err error) {
+	arr := &ArrayNode{Elements: make([]Node, 0)}
+	arr.Start = index.position(tokens[start].Start)
+	i := start + 1
+	for i < len(tokens) && tokens[i].Type != ']' {
+		var element Node
+		element, i, err = astTokens(index, tokens, i)
+		if err != nil {
+			return nil, 0, err
+		}
+		arr.Elements = append(arr.Elements, element)
+		if i < len(tokens) && tokens[i].Type == ',' {
+			i++
+			if i < len(tokens) && tokens[i].Type == ']' {
+				return nil, 0, ErrArray
+			}
+		} else {
+			break
+		}
+	}
+	if i >= len(tokens) || tokens[i].Type != ']' {
+		return nil, 0, ErrArray
+	}
+	arr.End = index.position(tokens[i].End)
+	return arr, i + 1, nil
+}
+
+func astObject(index *lineIndex, tokens []*Token, start int) (node Node, end int, err error) {
+	obj := &ObjectNode{Members: make([]*MemberNode, 0)}
+	obj.Start = index.position(tokens[start].Start)
+	i := start + 1
+	for i < len(tokens) && tokens[i].Type != '}' {
+		if tokens[i].Type != 'S' || i+1 >= len(tokens) || tokens[i+1].Type != ':' {
+			return nil, 0, ErrObject
+		}
+		key := &StringNode{index.span(tokens[i].Start, tokens[i].End), tokens[i].Content}
+		var value Node
+		value, i, err = astTokens(index, tokens, i+2)
+		if err != nil {
+			return nil, 0, err
+		}
+		obj.Members = append(obj.Members, &MemberNode{Span{key.Start, value.Location().End}, key, value})
+		if i < len(tokens) && tokens[i].Type == ',' {
+			i++
+			if i < len(tokens) && tokens[i].Type == '}' {
+				return nil, 0, ErrObject
+			}
+		} else {
+			break
+		}
+	}
+	if i >= len(tokens) || tokens[i].Type != '}' {
+		return nil, 0, ErrObject
+	}
+	obj.End = index.position(tokens[i].End)
+	return obj, i + 1, nil
+}
+
+// printAST writes one line per node, with its span and its key or value
+func printAST(w io.Writer, node Node, indent string) {
+	span := node.Location()
+	location := fmt.Sprintf("%v-%v", span.Start, span.End)
+	switch n := node.(type) {
+	case *ObjectNode:
+		fmt.Fprintf(w, "%sObject %s\n", indent, location)
+		for _, member := range n.Members {
+			fmt.Fprintf(w, "%s  Member %s %v-%v\n", indent, quote(member.Key.Text), member.Start, member.End)
+			printAST(w, member.Value, indent+strings.Repeat(" ", 4))
+		}
+	case *ArrayNode:
+		fmt.Fprintf(w, "%sArray %s\n", indent, location)
+		for _, element := range n.Elements {
+			printAST(w, element, indent+"  ")
+		}
+	case *StringNode:
+		fmt.Fprintf(w, "%sString %s %s\n", indent, location, quote(n.Text))
+	case *NumberNode:
+		fmt.Fprintf(w, "%sNumber %s %s\n", indent, location, n.Literal)
+	case *BoolNode:
+		fmt.Fprintf(w, "%sBool %s %t\n", indent, location, n.Value)
+	case *NullNode:
+		fmt.Fprintf(w, "%sNull %s\n", indent, location)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestASTSpans(t *testing.T) {
+	testCases := map[string]string{
+		`[1, "a"]`: "Array 1:1-1:9\n" +
+			"  Number 1:2-1:3 1\n" +
+			"  String 1:5-1:8 \"a\"\n",
+		"{\"ключ\": \"日本\",\n  \"b\": [1, true]}": "Object 1:1-2:18\n" +
+			"  Member \"ключ\" 1:2-1:14\n" +
+			"    String 1:10-1:14 \"日本\"\n" +
+			"  Member \"b\" 2:3-2:17\n" +
+			"    Array 2:8-2:17\n" +
+			"      Number 2:9-2:10 1\n" +
+			"      Bool 2:12-2:16 true\n",
+		"[{\"é\":[null]},\n\n {}]": "Array 1:1-3:5\n" +
+			"  Object 1:2-1:14\n" +
+			"    Member \"é\" 1:3-1:13\n" +
+			"      Array 1:7-1:13\n" +
+			"        Null 1:8-1:12\n" +
+			"  Object 3:2-3:4\n",
+	}
+	for input, want := range testCases {
+		node, err := parseAST(input)
+		if err != nil {
+			t.Errorf("%q: %v", input, err)
+			continue
+		}
+		var sb strings.Builder
+		printAST(&sb, node, "")
+		if got := sb.String(); got != want {
+			t.Errorf("%q: want:\n%s\ngot:\n%s", input, want, got)
+		}
+	}
+}
+
+func TestLineIndexOutOfOrder(t *testing.T) {
+	data := "aé日\nb\n\nçd"
+	index := newLineIndex(data)
+	for offset := len(data); offset >= 0; offset-- {
+		if !utf8.RuneStart(data[min(offset, len(data)-1)]) {
+			continue
+		}
+		before := data[:offset]
+		line := strings.Count(before, "\n") + 1
+		column := utf8.RuneCountInString(before[strings.LastIndexByte(before, '\n')+1:]) + 1
+		for _, got := range []Position{index.position(offset), index.position(offset)} {
+			if got.Line != line || got.Column != column {
+				t.Errorf("%d: want: %d:%d - got: %v", offset, line, column, got)
+			}
+		}
+	}
+}
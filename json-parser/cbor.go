@@ -0,0 +1,505 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var ErrCBOR = errors.New("invalid cbor data")
+
+const (
+	cborUint byte = iota
+	cborNegInt
+	cborBytes
+	cborText
+	cborArray
+	cborMap
+	cborTag
+	cborSimple
+)
+
+// cborIndefinite is the additional information for indefinite length items,
+// and the break code ending them
+const (
+	cborIndefinite = 31
+	cborBreak      = 0xff
+)
+
+// encodeCBOR writes value using the preferred serialization of RFC 8949, with
+// the shortest heads, the shortest float that keeps the value and map keys in
+// bytewise lexicographic order of their encoding
+func encodeCBOR(value interface{}) []byte {
+	return appendCBOR(nil, value)
+}
+
+func appendCBOR(out []byte, value interface{}) []byte {
+	switch v := value.(type) {
+	case nil:
+		return append(out, cborSimple<<5|22)
+	case bool:
+		if v {
+			return append(out, cborSimple<<5|21)
+		}
+		return append(out, cborSimple<<5|20)
+	case float64, Number:
+		i, u, f, kind := splitNumber(v)
+		switch {
+		case kind == numberUint:
+			return appendCBORHead(out, cborUint, u)
+		case kind == numberInt && i >= 0:
+			return appendCBORHead(out, cborUint, uint64(i))
+		case kind == numberInt:
+			return appendCBORHead(out, cborNegInt, uint64(-1-i))
+		}
+		if n, ok := v.(Number); ok {
+			if arg, ok := cborNegativeArg(n); ok {
+				return appendCBORHead(out, cborNegInt, arg)
+			}
+		}
+		if h, ok := float16Bits(f); ok {
+			return binary.BigEndian.AppendUint16(append(out, cborSimple<<5|25), h)
+		}
+		if float64(float32(f)) == f {
+			return binary.BigEndian.AppendUint32(append(out, cborSimple<<5|26), math.Float32bits(float32(f)))
+		}
+		return binary.BigEndian.AppendUint64(append(out, cborSimple<<5|27), math.Float64bits(f))
+	case string:
+		out = appendCBORHead(out, cborText, uint64(len(v)))
+		return append(out, v...)
+	case []interface{}:
+		out = appendCBORHead(out, cborArray, uint64(len(v)))
+		for _, item := range v {
+			out = appendCBOR(out, item)
+		}
+		return out
+	case map[string]interface{}:
+		keys := sortedKeys(v)
+		slices.SortStableFunc(keys, func(a, b string) int {
+			return len(a) - len(b)
+		})
+		out = appendCBORHead(out, cborMap, uint64(len(v)))
+		for _, key := range keys {
+			out = appendCBOR(out, key)
+			out = appendCBOR(out, v[key])
+		}
+		return out
+	}
+	return out
+}
+
+// cborNegativeArg gives the argument of major type 1 for the integers below
+// the range of int64, down to -2^64
+func cborNegativeArg(n Number) (uint64, bool) {
+	i, ok := new(big.Int).SetString(string(n), 10)
+	if !ok || i.Sign() >= 0 {
+		return 0, false
+	}
+	i.Neg(i).Sub(i, big.NewInt(1))
+	return i.Uint64(), i.IsUint64()
+}
+
+func appendCBORHead(out []byte, major byte, arg uint64) []byte {
+	major <<= 5
+	switch {
+	case arg < 24:
+		return append(out, major|byte(arg))
+	case arg <= math.MaxUint8:
+		return append(out, major|24, byte(arg))
+	case arg <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(out, major|25), uint16(arg))
+	case arg <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(out, major|26), uint32(arg))
+	default:
+		return binary.BigEndian.AppendUint64(append(out, major|27), arg)
+	}
+}
+
+// float16Bits converts f to IEEE 754 half precision, if that can be done
+// without losing precision
+func float16Bits(f float64) (uint16, bool) {
+	if float64(float32(f)) != f {
+		return 0, false
+	}
+	bits := math.Float32bits(float32(f))
+	sign := uint16(bits>>16) & 0x8000
+	exp := int(bits>>23&0xff) - 127
+	mant := bits & 0x7fffff
+	switch {
+	case f == 0:
+		return sign, true
+	case exp >= -14 && exp <= 15 && mant&0x1fff == 0:
+		return sign | uint16(exp+15)<<10 | uint16(mant>>13), true
+	case exp >= -24 && exp < -14:
+		// subnormal, the implicit leading bit becomes part of the mantissa
+		shift := uint(-1 - exp)
+		full := mant | 0x800000
+		if full&(1<<shift-1) == 0 {
+			return sign | uint16(full>>shift), true
+		}
+	}
+	return 0, false
+}
+
+func float16Value(h uint16) float64 {
+	exp := int(h >> 10 & 0x1f)
+	mant := float64(h & 0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		f = -f
+	}
+	return f
+}
+
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+// decodeCBOR converts a single CBOR item into the parser's value model, as
+// suggested by RFC 8949 section 6.1: byte strings become base64url strings,
+// non-text map keys are replaced by their JSON text, bignums become numbers and
+// other tags are dropped
+func decodeCBOR(data []byte) (result interface{}, err error) {
+	if len(data) == 0 {
+		return nil, ErrEmpty
+	}
+	d := &cborDecoder{data: data}
+	result, err = d.decode()
+	if err == nil && d.pos < len(data) {
+		err = ErrCBOR
+	}
+	return
+}
+
+func (d *cborDecoder) read(n uint64) ([]byte, error) {
+	if uint64(len(d.data)-d.pos) < n {
+		return nil, ErrCBOR
+	}
+	d.pos += int(n)
+	return d.data[d.pos-int(n) : d.pos], nil
+}
+
+func (d *cborDecoder) head() (major, info byte, arg uint64, err error) {
+	b, err := d.read(1)
+	if err != nil {
+		return
+	}
+	major, info = b[0]>>5, b[0]&0x1f
+	switch {
+	case info < 24:
+		arg = uint64(info)
+	case info <= 27:
+		b, err = d.read(1 << (info - 24))
+		for _, c := range b {
+			arg = arg<<8 | uint64(c)
+		}
+	case info == cborIndefinite && major >= cborBytes && major <= cborMap:
+	default:
+		err = ErrCBOR
+	}
+	return
+}
+
+func (d *cborDecoder) atBreak() bool {
+	if d.pos < len(d.data) && d.data[d.pos] == cborBreak {
+		d.pos++
+		return true
+	}
+	return false
+}
+
+func (d *cborDecoder) decode() (result interface{}, err error) {
+	depth++
+	defer func() {
+		depth--
+	}()
+	if depth > maxDepth {
+		return nil, ErrMaxDepth
+	}
+
+	major, info, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case cborUint:
+		return Number(strconv.FormatUint(arg, 10)), nil
+	case cborNegInt:
+		return Number(negativeInt(arg).String()), nil
+	case cborBytes:
+		b, err := d.byteString(major, info, arg)
+		return base64.RawURLEncoding.EncodeToString(b), err
+	case cborText:
+		b, err := d.byteString(major, info, arg)
+		return string(b), err
+	case cborArray:
+		arr := make([]interface{}, 0)
+		for i := uint64(0); info == cborIndefinite || i < arg; i++ {
+			if info == cborIndefinite && d.atBreak() {
+				break
+			}
+			item, err := d.decode()
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, item)
+		}
+		return arr, nil
+	case cborMap:
+		obj := make(map[string]interface{})
+		for i := uint64(0); info == cborIndefinite || i < arg; i++ {
+			if info == cborIndefinite && d.atBreak() {
+				break
+			}
+			key, err := d.decode()
+			if err != nil {
+				return nil, err
+			}
+			value, err := d.decode()
+			if err != nil {
+				return nil, err
+			}
+			if s, ok := key.(string); ok {
+				obj[s] = value
+			} else {
+				obj[marshal(key)] = value
+			}
+		}
+		return obj, nil
+	case cborTag:
+		if arg == 2 || arg == 3 {
+			if d.pos < len(d.data) && d.data[d.pos]>>5 == cborBytes {
+				bytesMajor, bytesInfo, bytesArg, err := d.head()
+				if err != nil {
+					return nil, err
+				}
+				b, err := d.byteString(bytesMajor, bytesInfo, bytesArg)
+				if err != nil {
+					return nil, err
+				}
+				n := new(big.Int).SetBytes(b)
+				if arg == 3 {
+					n.Neg(n).Sub(n, big.NewInt(1))
+				}
+				return Number(n.String()), nil
+			}
+		}
+		return d.decode()
+	default:
+		switch info {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 25:
+			return cborFloat(float16Value(uint16(arg))), nil
+		case 26:
+			return cborFloat(float64(math.Float32frombits(uint32(arg)))), nil
+		case 27:
+			return cborFloat(math.Float64frombits(arg)), nil
+		}
+		// null, undefined and unassigned simple values
+		return nil, nil
+	}
+}
+
+func negativeInt(arg uint64) *big.Int {
+	n := new(big.Int).SetUint64(arg)
+	return n.Neg(n).Sub(n, big.NewInt(1))
+}
+
+func cborFloat(f float64) interface{} {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil
+	}
+	return f
+}
+
+// byteString reads the content of a byte or text string, joining the chunks
+// of indefinite length strings
+func (d *cborDecoder) byteString(major, info byte, arg uint64) ([]byte, error) {
+	if info != cborIndefinite {
+		return d.read(arg)
+	}
+	var result []byte
+	for !d.atBreak() {
+		chunkMajor, chunkInfo, chunkArg, err := d.head()
+		if err != nil {
+			return nil, err
+		}
+		if chunkMajor != major || chunkInfo == cborIndefinite {
+			return nil, ErrCBOR
+		}
+		chunk, err := d.read(chunkArg)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, chunk...)
+	}
+	return result, nil
+}
+
+// cborDiagnostic returns the diagnostic notation of RFC 8949 section 8 for a
+// single CBOR item, keeping everything the conversion to JSON would lose
+func cborDiagnostic(data []byte) (string, error) {
+	if len(data) == 0 {
+		return "", ErrEmpty
+	}
+	d := &cborDecoder{data: data}
+	var sb strings.Builder
+	err := d.diagnostic(&sb)
+	if err == nil && d.pos < len(data) {
+		err = ErrCBOR
+	}
+	return sb.String(), err
+}
+
+func (d *cborDecoder) diagnostic(sb *strings.Builder) error {
+	depth++
+	defer func() {
+		depth--
+	}()
+	if depth > maxDepth {
+		return ErrMaxDepth
+	}
+
+	major, info, arg, err := d.head()
+	if err != nil {
+		return err
+	}
+	switch major {
+	case cborUint:
+		sb.WriteString(strconv.FormatUint(arg, 10))
+	case cborNegInt:
+		sb.WriteString(negativeInt(arg).String())
+	case cborBytes, cborText:
+		if info == cborIndefinite {
+			sb.WriteString("(_ ")
+			for i := 0; !d.atBreak(); i++ {
+				if i > 0 {
+					sb.WriteString(", ")
+				}
+				chunkMajor, chunkInfo, chunkArg, err := d.head()
+				if err != nil {
+					return err
+				}
+				if chunkMajor != major || chunkInfo == cborIndefinite {
+					return ErrCBOR
+				}
+				chunk, err := d.read(chunkArg)
+				if err != nil {
+					return err
+				}
+				writeCBORString(sb, major, chunk)
+			}
+			sb.WriteString(")")
+			return nil
+		}
+		b, err := d.read(arg)
+		if err != nil {
+			return err
+		}
+		writeCBORString(sb, major, b)
+	case cborArray, cborMap:
+		open, close := "[", "]"
+		if major == cborMap {
+			open, close = "{", "}"
+		}
+		sb.WriteString(open)
+		if info == cborIndefinite {
+			sb.WriteString("_ ")
+		}
+		for i := uint64(0); info == cborIndefinite || i < arg; i++ {
+			if info == cborIndefinite && d.atBreak() {
+				break
+			}
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			if err := d.diagnostic(sb); err != nil {
+				return err
+			}
+			if major == cborMap {
+				sb.WriteString(": ")
+				if err := d.diagnostic(sb); err != nil {
+					return err
+				}
+			}
+		}
+		sb.WriteString(close)
+	case cborTag:
+		sb.WriteString(strconv.FormatUint(arg, 10))
+		sb.WriteString("(")
+		if err := d.diagnostic(sb); err != nil {
+			return err
+		}
+		sb.WriteString(")")
+	default:
+		switch info {
+		case 20:
+			sb.WriteString("false")
+		case 21:
+			sb.WriteString("true")
+		case 22:
+			sb.WriteString("null")
+		case 23:
+			sb.WriteString("undefined")
+		case 25:
+			writeCBORFloat(sb, float16Value(uint16(arg)), "_1")
+		case 26:
+			writeCBORFloat(sb, float64(math.Float32frombits(uint32(arg))), "_2")
+		case 27:
+			writeCBORFloat(sb, math.Float64frombits(arg), "_3")
+		default:
+			sb.WriteString("simple(" + strconv.FormatUint(arg, 10) + ")")
+		}
+	}
+	return nil
+}
+
+func writeCBORString(sb *strings.Builder, major byte, b []byte) {
+	if major == cborBytes {
+		sb.WriteString("h'" + hex.EncodeToString(b) + "'")
+	} else {
+		sb.WriteString(quote(string(b)))
+	}
+}
+
+// writeCBORFloat always includes a decimal point or exponent, so floats can be
+// told apart from integers, followed by the encoding indicator for the size
+func writeCBORFloat(sb *strings.Builder, f float64, indicator string) {
+	switch {
+	case math.IsNaN(f):
+		sb.WriteString("NaN")
+	case math.IsInf(f, 1):
+		sb.WriteString("Infinity")
+	case math.IsInf(f, -1):
+		sb.WriteString("-Infinity")
+	default:
+		s := strconv.FormatFloat(f, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		sb.WriteString(s)
+	}
+	sb.WriteString(indicator)
+}
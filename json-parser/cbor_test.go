@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// withNumbers parses the documents of a test as the conversions do, keeping
+// the literals of the numbers
+func withNumbers(t *testing.T) {
+	saved := useNumber
+	useNumber = true
+	t.Cleanup(func() {
+		useNumber = saved
+	})
+}
+
+// decodeSafely turns a panic of a decoder into an error
+func decodeSafely(decode func([]byte) (interface{}, error), data []byte) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			depth = 0
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return decode(data)
+}
+
+// TestCBOREncode uses the examples of RFC 8949 appendix A that JSON can hold
+func TestCBOREncode(t *testing.T) {
+	withNumbers(t)
+	testCases := map[string]string{
+		`0`:                         "00",
+		`1`:                         "01",
+		`10`:                        "0a",
+		`23`:                        "17",
+		`24`:                        "1818",
+		`25`:                        "1819",
+		`100`:                       "1864",
+		`1000`:                      "1903e8",
+		`1000000`:                   "1a000f4240",
+		`1000000000000`:             "1b000000e8d4a51000",
+		`18446744073709551615`:      "1bffffffffffffffff",
+		`-18446744073709551616`:     "3bffffffffffffffff",
+		`-9223372036854775809`:      "3b8000000000000000",
+		`-1`:                        "20",
+		`-10`:                       "29",
+		`-100`:                      "3863",
+		`-1000`:                     "3903e7",
+		`0.0`:                       "f90000",
+		`-0.0`:                      "f98000",
+		`1.0`:                       "f93c00",
+		`1.1`:                       "fb3ff199999999999a",
+		`1.5`:                       "f93e00",
+		`65504.0`:                   "f97bff",
+		`100000.0`:                  "fa47c35000",
+		`3.4028234663852886e+38`:    "fa7f7fffff",
+		`1.0e+300`:                  "fb7e37e43c8800759c",
+		`5.960464477539063e-8`:      "f90001",
+		`0.00006103515625`:          "f90400",
+		`-4.0`:                      "f9c400",
+		`-4.1`:                      "fbc010666666666666",
+		`false`:                     "f4",
+		`true`:                      "f5",
+		`null`:                      "f6",
+		`""`:                        "60",
+		`"a"`:                       "6161",
+		`"IETF"`:                    "6449455446",
+		`"\"\\"`:                    "62225c",
+		`"ü"`:                       "62c3bc",
+		`"水"`:                       "63e6b0b4",
+		`[]`:                        "80",
+		`[1, 2, 3]`:                 "83010203",
+		`[1, [2, 3], [4, 5]]`:       "8301820203820405",
+		`{}`:                        "a0",
+		`{"a": 1, "b": [2, 3]}`:     "a26161016162820203",
+		`["a", {"b": "c"}]`:         "826161a161626163",
+		`{"bb": 1, "a": 2, "c": 3}`: "a361610261630362626201",
+		`[1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24, 25]`: "98190102030405060708090a0b0c0d0e0f101112131415161718181819",
+	}
+	for input, want := range testCases {
+		value, err := parse(input)
+		if err != nil {
+			t.Errorf("%s: %v", input, err)
+		} else if got := hex.EncodeToString(encodeCBOR(value)); got != want {
+			t.Errorf("%s: want: %s - got: %s", input, want, got)
+		}
+	}
+}
+
+func TestCBORDecode(t *testing.T) {
+	testCases := map[string]string{
+		"1bffffffffffffffff":         `18446744073709551615`,
+		"3bffffffffffffffff":         `-18446744073709551616`,
+		"c249010000000000000000":     `18446744073709551616`,
+		"c349010000000000000000":     `-18446744073709551617`,
+		"f93e00":                     `1.5`,
+		"f97c00":                     `null`,
+		"fa7fc00000":                 `null`,
+		"f7":                         `null`,
+		"f0":                         `null`,
+		"4401020304":                 `"AQIDBA"`,
+		"c11a514b67b0":               `1363896240`,
+		"d74401020304":               `"AQIDBA"`,
+		"5f42010243030405ff":         `"AQIDBAU"`,
+		"7f657374726561646d696e67ff": `"streaming"`,
+		"9fff":                       `[]`,
+		"9f018202039f0405ffff":       `[1,[2,3],[4,5]]`,
+		"bf61610161629f0203ffff":     `{"a":1,"b":[2,3]}`,
+		"a201020304":                 `{"1":2,"3":4}`,
+	}
+	for input, want := range testCases {
+		data, _ := hex.DecodeString(input)
+		if value, err := decodeSafely(decodeCBOR, data); err != nil {
+			t.Errorf("%s: %v", input, err)
+		} else if got := marshal(value); got != want {
+			t.Errorf("%s: want: %s - got: %s", input, want, got)
+		}
+	}
+}
+
+func TestCBORRoundTrip(t *testing.T) {
+	withNumbers(t)
+	documents := []string{
+		`{"a":[1,-2,0.5,"x",null,true,false],"b":{"":""},"c":[]}`,
+		`[18446744073709551615,-18446744073709551616,-9223372036854775808,0.1]`,
+		`"\u0000é😀"`,
+	}
+	for _, document := range documents {
+		value, err := parse(document)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded := encodeCBOR(value)
+		decoded, err := decodeSafely(decodeCBOR, encoded)
+		if err != nil {
+			t.Errorf("%s: %v", document, err)
+		} else if got, want := marshal(decoded), marshal(value); got != want {
+			t.Errorf("%s: want: %s - got: %s", document, want, got)
+		}
+		// no proper prefix of an item is an item
+		for end := 0; end < len(encoded); end++ {
+			if _, err := decodeSafely(decodeCBOR, encoded[:end]); err == nil || strings.HasPrefix(err.Error(), "panic:") {
+				t.Errorf("%s: %x: want an error - got: %v", document, encoded[:end], err)
+			}
+		}
+	}
+}
+
+func TestCBORInvalid(t *testing.T) {
+	for _, input := range []string{
+		"1c",       // reserved additional information
+		"1f",       // indefinite length integer
+		"ff",       // break without an item
+		"0000",     // trailing data
+		"5f01ff",   // integer chunk in a byte string
+		"5f5fffff", // indefinite chunk in a byte string
+		"7f4161ff", // byte string chunk in a text string
+		"9f01",     // unfinished indefinite array
+		"bf6161ff", // key without a value
+		"c2",       // tag without an item
+		"5b7fffffffffffffff",
+		"9bffffffffffffffff",
+	} {
+		data, _ := hex.DecodeString(input)
+		if _, err := decodeSafely(decodeCBOR, data); err == nil || strings.HasPrefix(err.Error(), "panic:") {
+			t.Errorf("%s: want an error - got: %v", input, err)
+		}
+		diagnostic := func(data []byte) (interface{}, error) {
+			return cborDiagnostic(data)
+		}
+		if _, err := decodeSafely(diagnostic, data); err == nil || strings.HasPrefix(err.Error(), "panic:") {
+			t.Errorf("%s: want an error from the diagnostic - got: %v", input, err)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The conformance tests run both backends over the test_parsing files of
+// JSONTestSuite (https://github.com/nst/JSONTestSuite), read from the
+// directory in JSON_TEST_SUITE or from JSONTestSuite/test_parsing, and over
+// the json_checker files. Files starting with y_ must be accepted and n_
+// rejected. The ones starting with i_ are left to the implementation, and
+// their outcome is compared to the behavior documented in
+// implementationDefined, so that any change is noticed.
+
+// implementationDefined documents how the i_ cases of the suite are handled,
+// with the content of each file:
+//   - numbers out of the range of float64 are rejected, while integers with
+//     more digits than it keeps are accepted and rounded, and numbers too
+//     small for it are read as 0
+//   - strings with lone surrogates or invalid UTF-8 are accepted, the
+//     recursive descent backend replacing them with U+FFFD
+//   - byte order marks and UTF-16 are rejected
+//   - nesting is only limited by -max-depth
+var implementationDefined = map[string]struct {
+	input    string
+	accepted bool
+}{
+	"i_number_double_huge_neg_exp.json":                   {`[123.456e-789]`, true},
+	"i_number_huge_exp.json":                              {`[0.4e` + strings.Repeat("9", 300) + `006]`, false},
+	"i_number_neg_int_huge_exp.json":                      {`[-1e+9999]`, false},
+	"i_number_pos_double_huge_exp.json":                   {`[1.5e+9999]`, false},
+	"i_number_real_neg_overflow.json":                     {`[-123123e100000]`, false},
+	"i_number_real_pos_overflow.json":                     {`[123123e100000]`, false},
+	"i_number_real_underflow.json":                        {`[123e-10000000]`, true},
+	"i_number_too_big_neg_int.json":                       {`[-123123123123123123123123123123]`, true},
+	"i_number_too_big_pos_int.json":                       {`[100000000000000000000]`, true},
+	"i_number_very_big_negative_int.json":                 {`[-237462374673276894279832749832423479823246327846]`, true},
+	"i_object_key_lone_2nd_surrogate.json":                {`{"\uDFAA":0}`, true},
+	"i_string_1st_surrogate_but_2nd_missing.json":         {`["\uDADA"]`, true},
+	"i_string_1st_valid_surrogate_2nd_invalid.json":       {`["\uD888\u1234"]`, true},
+	"i_string_UTF-16LE_with_BOM.json":                     {"\xff\xfe[\x00\"\x00\xe9\x00\"\x00]\x00", false},
+	"i_string_UTF-8_invalid_sequence.json":                {"[\"\xe6\x97\xa5\xd1\x88\xfa\"]", true},
+	"i_string_UTF8_surrogate_U+D800.json":                 {"[\"\xed\xa0\x80\"]", true},
+	"i_string_incomplete_surrogate_and_escape_valid.json": {`["\uD800\n"]`, true},
+	"i_string_incomplete_surrogate_pair.json":             {`["\uDd1ea"]`, true},
+	"i_string_incomplete_surrogates_escape_valid.json":    {`["\uD800\uD800\n"]`, true},
+	"i_string_invalid_lonely_surrogate.json":              {`["\ud800"]`, true},
+	"i_string_invalid_surrogate.json":                     {`["\ud800abc"]`, true},
+	"i_string_invalid_utf-8.json":                         {"[\"\xff\"]", true},
+	"i_string_inverted_surrogates_U+1D11E.json":           {`["\uDd1e\uD834"]`, true},
+	"i_string_iso_latin_1.json":                           {"[\"\xe9\"]", true},
+	"i_string_lone_second_surrogate.json":                 {`["\uDFAA"]`, true},
+	"i_string_lone_utf8_continuation_byte.json":           {"[\"\x81\"]", true},
+	"i_string_not_in_unicode_range.json":                  {"[\"\xf4\xbf\xbf\xbf\"]", true},
+	"i_string_overlong_sequence_2_bytes.json":             {"[\"\xc0\xaf\"]", true},
+	"i_string_overlong_sequence_6_bytes.json":             {"[\"\xfc\x83\xbf\xbf\xbf\xbf\"]", true},
+	"i_string_overlong_sequence_6_bytes_null.json":        {"[\"\xfc\x80\x80\x80\x80\x80\"]", true},
+	"i_string_truncated-utf-8.json":                       {"[\"\xe0\xff\"]", true},
+	"i_string_utf16BE_no_BOM.json":                        {"\x00[\x00\"\x00\xe9\x00\"\x00]", false},
+	"i_string_utf16LE_no_BOM.json":                        {"[\x00\"\x00\xe9\x00\"\x00]\x00", false},
+	"i_structure_500_nested_arrays.json":                  {strings.Repeat("[", 500) + strings.Repeat("]", 500), true},
+	"i_structure_UTF-8_BOM_empty_object.json":             {"\xef\xbb\xbf{}", false},
+}
+
+var backends = []struct {
+	name string
+	tape bool
+}{{"descent", false}, {"tape", true}}
+
+// conformanceOutcome tells if a backend accepts data, with an error if it
+// crashes
+func conformanceOutcome(data string, tape bool) (accepted bool, err error) {
+	saved := useTape
+	defer func() {
+		useTape = saved
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	useTape = tape
+	_, parseErr := parse(data)
+	return parseErr == nil, nil
+}
+
+func TestImplementationDefined(t *testing.T) {
+	for name, c := range implementationDefined {
+		for _, backend := range backends {
+			accepted, err := conformanceOutcome(c.input, backend.tape)
+			if err != nil {
+				t.Errorf("%s: %s: %v", backend.name, name, err)
+			} else if accepted != c.accepted {
+				t.Errorf("%s: %s: accepted %t, documented %t", backend.name, name, accepted, c.accepted)
+			}
+		}
+	}
+}
+
+// TestTruncated checks that every prefix of valid documents is rejected
+// without a crash, including the unclosed cases of the suite such as
+// n_structure_object_unclosed_no_value.json
+func TestTruncated(t *testing.T) {
+	documents := []string{
+		`{"": 1}`,
+		`{"a": [1, {"b": null}], "c": "d"}`,
+		`[1, [true, false], {"x": -1.5e3}]`,
+		`"text"`,
+	}
+	for _, document := range documents {
+		for end := 0; end < len(document); end++ {
+			prefix := document[:end]
+			if strings.TrimSpace(prefix) == "" {
+				continue
+			}
+			for _, backend := range backends {
+				if accepted, err := conformanceOutcome(prefix, backend.tape); err != nil {
+					t.Errorf("%s: %q: %v", backend.name, prefix, err)
+				} else if accepted {
+					t.Errorf("%s: %q: accepted", backend.name, prefix)
+				}
+			}
+		}
+	}
+}
+
+// conformanceCounts is a cell of the matrix, for a backend and a category
+type conformanceCounts struct {
+	files, accepted, expected, crashed int
+}
+
+func TestJSONTestSuite(t *testing.T) {
+	dir := os.Getenv("JSON_TEST_SUITE")
+	if dir == "" {
+		dir = filepath.Join("JSONTestSuite", "test_parsing")
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) == 0 && os.Getenv("CI") != "" {
+		t.Fatalf("JSONTestSuite not found in %s, set JSON_TEST_SUITE to its test_parsing directory", dir)
+	}
+	if len(files) == 0 {
+		t.Skipf("JSONTestSuite not found in %s, set JSON_TEST_SUITE to its test_parsing directory", dir)
+	}
+
+	matrix := map[string]*conformanceCounts{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := filepath.Base(file)
+		category := name[:1]
+		documented, isDocumented := implementationDefined[name]
+		if category == "i" && !isDocumented {
+			t.Logf("%s: behavior not documented", name)
+		}
+		for _, backend := range backends {
+			counts := matrix[backend.name+category]
+			if counts == nil {
+				counts = &conformanceCounts{}
+				matrix[backend.name+category] = counts
+			}
+			counts.files++
+			accepted, err := conformanceOutcome(string(data), backend.tape)
+			if err != nil {
+				counts.crashed++
+				t.Errorf("%s: %s: %v", backend.name, name, err)
+				continue
+			}
+			if accepted {
+				counts.accepted++
+			}
+			switch {
+			case category == "y" && !accepted:
+				t.Errorf("%s: %s: rejected", backend.name, name)
+			case category == "n" && accepted:
+				t.Errorf("%s: %s: accepted", backend.name, name)
+			case category == "i" && isDocumented && accepted != documented.accepted:
+				t.Errorf("%s: %s: accepted %t, documented %t", backend.name, name, accepted, documented.accepted)
+			default:
+				counts.expected++
+			}
+		}
+	}
+
+	t.Logf("%-8s %-10s %6s %9s %9s %8s", "backend", "category", "files", "accepted", "expected", "crashed")
+	for _, backend := range backends {
+		for _, category := range []string{"y", "n", "i"} {
+			if counts := matrix[backend.name+category]; counts != nil {
+				t.Logf("%-8s %-10s %6d %9d %9d %8d", backend.name, category+"_", counts.files, counts.accepted, counts.expected, counts.crashed)
+			}
+		}
+	}
+}
+
+func TestJSONChecker(t *testing.T) {
+	savedPayloadOnly, savedMaxDepth := payloadOnly, maxDepth
+	payloadOnly, maxDepth = true, 20
+	defer func() {
+		payloadOnly, maxDepth = savedPayloadOnly, savedMaxDepth
+	}()
+	files, _ := filepath.Glob("json_checker/*.json")
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := strings.HasPrefix(filepath.Base(file), "pass")
+		for _, backend := range backends {
+			if accepted, err := conformanceOutcome(string(data), backend.tape); err != nil {
+				t.Errorf("%s: %s: %v", backend.name, file, err)
+			} else if accepted != want {
+				t.Errorf("%s: %s: accepted %t", backend.name, file, accepted)
+			}
+		}
+	}
+}
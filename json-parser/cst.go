@@ -0,0 +1,288 @@
+package main
+
+import (
+	"strings"
+)
+
+// cstDocument is a concrete syntax tree of a document. Nodes only point at the
+// offsets of their tokens in the source, so whatever sits between tokens
+// (whitespace and comments) is kept as is by edits, which replace only the
+// bytes of the values affected.
+type cstDocument struct {
+	source string
+	root   *cstNode
+}
+
+type cstNode struct {
+	kind       byte
+	start, end int
+	children   []*cstChild
+}
+
+// cstChild is an array element or an object member. For elements, keyStart is
+// the same as the start of the value. comma is the offset of the separator
+// following the child, or -1 for the last one.
+type cstChild struct {
+	key              string
+	keyStart, keyEnd int
+	value            *cstNode
+	comma            int
+}
+
+func parseCST(source string) (*cstDocument, error) {
+	if _, err := parseValue(source); err != nil {
+		return nil, err
+	}
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	root, _, err := buildCST(tokens, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &cstDocument{source, root}, nil
+}
+
+func buildCST(tokens []*Token, i int) (node *cstNode, next int, err error) {
+	if i >= len(tokens) {
+		return nil, 0, ErrToken
+	}
+	open := tokens[i]
+	node = &cstNode{kind: open.Type, start: open.Start, end: open.End}
+	switch open.Type {
+	case 'S', '0', 't', 'f', 'n':
+		return node, i + 1, nil
+	case '[', '{':
+	default:
+		return nil, 0, ErrToken
+	}
+
+	close, errInvalid := byte(']'), ErrArray
+	if open.Type == '{' {
+		close, errInvalid = '}', ErrObject
+	}
+	i++
+	for i < len(tokens) && tokens[i].Type != close {
+		child := &cstChild{comma: -1}
+		if open.Type == '{' {
+			if tokens[i].Type != 'S' || i+1 >= len(tokens) || tokens[i+1].Type != ':' {
+				return nil, 0, ErrObject
+			}
+			child.key, child.keyStart, child.keyEnd = tokens[i].Content, tokens[i].Start, tokens[i].End
+			i += 2
+		}
+		child.value, i, err = buildCST(tokens, i)
+		if err != nil {
+			return nil, 0, err
+		}
+		if open.Type == '[' {
+			child.keyStart, child.keyEnd = child.value.start, child.value.start
+		}
+		node.children = append(node.children, child)
+		if i >= len(tokens) || tokens[i].Type != ',' {
+			break
+		}
+		child.comma = tokens[i].Start
+		i++
+	}
+	if i >= len(tokens) || tokens[i].Type != close {
+		return nil, 0, errInvalid
+	}
+	node.end = tokens[i].End
+	return node, i + 1, nil
+}
+
+// lookup returns the node referenced by parts, with its parent and position in
+// it (nil and -1 for the root)
+func (doc *cstDocument) lookup(parts []string) (node, parent *cstNode, index int, err error) {
+	node, index = doc.root, -1
+	for _, part := range parts {
+		parent = node
+		index = parent.find(part)
+		if index < 0 || index >= len(parent.children) {
+			return nil, nil, -1, ErrNotFound
+		}
+		node = parent.children[index].value
+	}
+	return
+}
+
+// find returns the position of the child referenced by part, the last one for
+// duplicate keys, or -1 if there is none. For arrays, "-" refers to the
+// position past the last element.
+func (node *cstNode) find(part string) int {
+	switch node.kind {
+	case '{':
+		for i := len(node.children) - 1; i >= 0; i-- {
+			if node.children[i].key == part {
+				return i
+			}
+		}
+	case '[':
+		if part == "-" {
+			return len(node.children)
+		}
+		if index, ok := arrayIndex(part); ok && index <= len(node.children) {
+			return index
+		}
+	}
+	return -1
+}
+
+func (doc *cstDocument) splice(start, end int, text string) string {
+	return doc.source[:start] + text + doc.source[end:]
+}
+
+// set replaces the value referenced by pointer, adding it as a new member or
+// element when the parent exists but the value doesn't
+func (doc *cstDocument) set(pointer, value string) (string, error) {
+	return doc.edit(pointer, value, false)
+}
+
+// insert adds value before the array element referenced by pointer, or past
+// the last one for "-". For objects it works the same as set.
+func (doc *cstDocument) insert(pointer, value string) (string, error) {
+	return doc.edit(pointer, value, true)
+}
+
+func (doc *cstDocument) edit(pointer, value string, shift bool) (string, error) {
+	parts, err := parsePointer(pointer)
+	if err != nil {
+		return "", err
+	}
+	if _, err := parseValue(value); err != nil {
+		return "", err
+	}
+	if len(parts) == 0 {
+		return doc.splice(doc.root.start, doc.root.end, value), nil
+	}
+	parent, _, _, err := doc.lookup(parts[:len(parts)-1])
+	if err != nil {
+		return "", err
+	}
+	last := parts[len(parts)-1]
+	index := parent.find(last)
+	switch {
+	case parent.kind == '{' && index >= 0:
+		child := parent.children[index]
+		return doc.splice(child.value.start, child.value.end, value), nil
+	case parent.kind == '{':
+		return doc.insertChild(parent, len(parent.children), quote(last)+doc.keySeparator(parent)+value), nil
+	case index < 0:
+		return "", ErrNotFound
+	case index < len(parent.children) && !shift:
+		child := parent.children[index]
+		return doc.splice(child.value.start, child.value.end, value), nil
+	default:
+		return doc.insertChild(parent, index, value), nil
+	}
+}
+
+// delete removes the value referenced by pointer, along with its key and one
+// of the separators around it. Comments are kept, as are the blank lines
+// around the member, while the line it was alone on goes with it.
+func (doc *cstDocument) delete(pointer string) (string, error) {
+	parts, err := parsePointer(pointer)
+	if err != nil {
+		return "", err
+	}
+	if len(parts) == 0 {
+		return "", ErrPointer
+	}
+	_, parent, index, err := doc.lookup(parts)
+	if err != nil {
+		return "", err
+	}
+	children := parent.children
+	child := children[index]
+	switch {
+	case index < len(children)-1:
+		start, end := doc.widen(child.keyStart, child.comma+1)
+		return doc.splice(start, end, ""), nil
+	case index == 0:
+		if doc.blank(parent.start+1, child.keyStart) && doc.blank(child.value.end, parent.end-1) {
+			return doc.splice(parent.start+1, parent.end-1, ""), nil
+		}
+		start, end := doc.widen(child.keyStart, child.value.end)
+		return doc.splice(start, end, ""), nil
+	}
+	comma := children[index-1].comma
+	if doc.blank(comma+1, child.keyStart) {
+		return doc.splice(comma, child.value.end, ""), nil
+	}
+	// the comments between the comma before the member and its key stay
+	start, end := doc.widen(child.keyStart, child.value.end)
+	return doc.source[:comma] + doc.source[comma+1:start] + doc.source[end:], nil
+}
+
+func (doc *cstDocument) blank(start, end int) bool {
+	return strings.TrimSpace(doc.source[start:end]) == ""
+}
+
+// widen extends the text between start and end over the spaces following it,
+// and over its whole line if nothing else is on it
+func (doc *cstDocument) widen(start, end int) (int, int) {
+	source := doc.source
+	end += len(source[end:]) - len(strings.TrimLeft(source[end:], " \t"))
+	lineStart := len(strings.TrimRight(source[:start], " \t"))
+	if lineStart > 0 && source[lineStart-1] != '\n' {
+		return start, end
+	}
+	switch {
+	case strings.HasPrefix(source[end:], "\n"):
+		return lineStart, end + 1
+	case strings.HasPrefix(source[end:], "\r\n"):
+		return lineStart, end + 2
+	}
+	return start, end
+}
+
+// insertChild places text before the child at index, or after the last one,
+// following the layout of its siblings
+func (doc *cstDocument) insertChild(parent *cstNode, index int, text string) string {
+	children := parent.children
+	if len(children) == 0 {
+		return doc.splice(parent.start+1, parent.start+1, text)
+	}
+	indent := doc.indentation(parent, max(min(index, len(children)-1), 1))
+	if index < len(children) {
+		at := children[index].keyStart
+		return doc.splice(at, at, text+","+indent)
+	}
+	at := children[len(children)-1].value.end
+	return doc.splice(at, at, ","+indent+text)
+}
+
+// indentation returns the whitespace before the child at index, up to the
+// line break if there is one
+func (doc *cstDocument) indentation(parent *cstNode, index int) string {
+	if index >= len(parent.children) {
+		index = 0
+	}
+	start := parent.start + 1
+	if index > 0 {
+		start = parent.children[index-1].comma + 1
+	}
+	trivia := doc.source[start:parent.children[index].keyStart]
+	if i := strings.LastIndexByte(trivia, '\n'); i >= 0 {
+		rest := trivia[i+1:]
+		return trivia[i : len(trivia)-len(strings.TrimLeft(rest, " \t"))]
+	}
+	if index == 0 {
+		return " "
+	}
+	return trivia[:len(trivia)-len(strings.TrimLeft(trivia, " \t"))]
+}
+
+// keySeparator returns the text between keys and values used by the other
+// members of an object
+func (doc *cstDocument) keySeparator(parent *cstNode) string {
+	for _, child := range parent.children {
+		separator := doc.source[child.keyEnd:child.value.start]
+		if !strings.Contains(separator, "/") {
+			return separator
+		}
+	}
+	return ": "
+}
@@ -0,0 +1,110 @@
+package main
+
+import "testing"
+
+func TestCSTEdit(t *testing.T) {
+	testCases := []struct {
+		op, input, pointer, value, want string
+	}{
+		{"set", `{"a": 1, "b": [1, 2]}`, "/b/1", `"x"`, `{"a": 1, "b": [1, "x"]}`},
+		{"set", `{"a": 1}`, "/b", `true`, `{"a": 1, "b": true}`},
+		{"set", "{\n  \"a\":1,\n  \"b\":2\n}\n", "/c", `[]`, "{\n  \"a\":1,\n  \"b\":2,\n  \"c\":[]\n}\n"},
+		{"set", `{}`, "/a", `{"b": null}`, `{"a": {"b": null}}`},
+		{"set", `[]`, "/-", `1`, `[1]`},
+		{"set", `[1, 2]`, "", `null`, `null`},
+		{"set", "{\n  // first\n  \"a\": 1 /* one */\n}  \n", "/a", `2`, "{\n  // first\n  \"a\": 2 /* one */\n}  \n"},
+		{"set", "{\n  \"a\": 1 // one\n}\n", "/b", `2`, "{\n  \"a\": 1,\n  \"b\": 2 // one\n}\n"},
+		{"insert", `[1, 2]`, "/0", `0`, `[0, 1, 2]`},
+		{"insert", `[1, 2]`, "/1", `"x"`, `[1, "x", 2]`},
+		{"insert", `[1, 2]`, "/-", `3`, `[1, 2, 3]`},
+		{"insert", "[\n\t1,\n\t2\n]", "/2", `3`, "[\n\t1,\n\t2,\n\t3\n]"},
+		{"insert", "[ // list\n  1\n]", "/0", `0`, "[ // list\n  0,\n  1\n]"},
+		{"delete", `{"a": 1, "b": 2, "c": 3}`, "/b", "", `{"a": 1, "c": 3}`},
+		{"delete", `{"a": 1, "b": 2}`, "/b", "", `{"a": 1}`},
+		{"delete", `[1, 2, 3]`, "/0", "", `[2, 3]`},
+		{"delete", "{\n  \"a\": 1, // one\n  \"b\": 2 // two\n}   \n", "/a", "", "{\n  // one\n  \"b\": 2 // two\n}   \n"},
+		{"delete", "[\n  1, /* one */\n  2\n]", "/1", "", "[\n  1 /* one */\n]"},
+		{"delete", "{ \"a\": [1] /* only */ }", "/a", "", "{ /* only */ }"},
+		{"delete", "{\n  \"a\": 1\n}", "/a", "", "{}"},
+		{"delete", "{\n  \"a\": 1,\n  // b\n  \"b\": 2\n}", "/a", "", "{\n  // b\n  \"b\": 2\n}"},
+		{"delete", "[\n  1,\n  // two\n  2\n]", "/1", "", "[\n  1\n  // two\n]"},
+		{"delete", "{\"a\": [\n  1\n]}", "/a/0", "", "{\"a\": []}"},
+	}
+	saved := relaxed
+	relaxed = true
+	defer func() {
+		relaxed = saved
+	}()
+	for _, c := range testCases {
+		doc, err := parseCST(c.input)
+		if err != nil {
+			t.Errorf("%q: %v", c.input, err)
+			continue
+		}
+		var got string
+		switch c.op {
+		case "set":
+			got, err = doc.set(c.pointer, c.value)
+		case "insert":
+			got, err = doc.insert(c.pointer, c.value)
+		case "delete":
+			got, err = doc.delete(c.pointer)
+		}
+		if err != nil {
+			t.Errorf("%s %q %s: %v", c.op, c.input, c.pointer, err)
+		} else if got != c.want {
+			t.Errorf("%s %q %s: want: %q - got: %q", c.op, c.input, c.pointer, c.want, got)
+		}
+	}
+}
+
+func TestCSTEditErrors(t *testing.T) {
+	doc, err := parseCST(`{"a": [1], "b": 2}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := doc.set("/x/y", "1"); err != ErrNotFound {
+		t.Errorf("set in a missing parent: want: %v - got: %v", ErrNotFound, err)
+	}
+	if _, err := doc.set("/a/2", "1"); err != ErrNotFound {
+		t.Errorf("set past the end: want: %v - got: %v", ErrNotFound, err)
+	}
+	if _, err := doc.set("/a", "[1,"); err == nil {
+		t.Errorf("set an invalid value: expected an error")
+	}
+	if _, err := doc.delete(""); err != ErrPointer {
+		t.Errorf("delete the root: want: %v - got: %v", ErrPointer, err)
+	}
+	if _, err := doc.delete("/a/1"); err != ErrNotFound {
+		t.Errorf("delete past the end: want: %v - got: %v", ErrNotFound, err)
+	}
+}
+
+// TestTokenPositions covers the offsets of the tokens the edits rely on, with
+// the comments allowed by -relaxed skipped between them
+func TestTokenPositions(t *testing.T) {
+	saved := relaxed
+	relaxed = true
+	defer func() {
+		relaxed = saved
+	}()
+	data := "[1, /* c */ \"é\" // x\n, true]"
+	tokens, err := tokenize(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"[", "1", ",", `"é"`, ",", "true", "]"}
+	if len(tokens) != len(want) {
+		t.Fatalf("want: %d tokens - got: %d", len(want), len(tokens))
+	}
+	for i, token := range tokens {
+		if got := data[token.Start:token.End]; got != want[i] {
+			t.Errorf("token %d: want: %q - got: %q", i, want[i], got)
+		}
+	}
+	for _, input := range []string{"[1 /* open", "[1, / 2]"} {
+		if _, err := tokenize(input); err == nil {
+			t.Errorf("%q: expected an error", input)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var ErrTable = errors.New("expected an array of objects")
+var ErrColumn = errors.New("duplicate column")
+
+// writeTable writes an array of objects as CSV rows, one column per key found
+// in any of the objects. Nested objects are flattened using dotted names, and
+// names that can't be read back apart are an error: a key with a dot giving
+// the same name as a nested member, or a column whose name followed by a dot
+// starts another one, in any of the rows.
+// Arrays are kept as JSON text. Strings are written as they are, so reading
+// the table back loses the empty ones and turns those holding JSON text, such
+// as "1" or "true", into numbers, booleans and so on.
+func writeTable(w io.Writer, value interface{}, comma rune) error {
+	arr, ok := value.([]interface{})
+	if !ok {
+		return ErrTable
+	}
+
+	var header []string
+	columns := make(map[string]int)
+	rows := make([]map[string]string, 0, len(arr))
+	for _, item := range arr {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			return ErrTable
+		}
+		row := make(map[string]string)
+		err := flattenRow(obj, "", row, func(name string) {
+			if _, ok := columns[name]; !ok {
+				columns[name] = len(header)
+				header = append(header, name)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		rows = append(rows, row)
+	}
+
+	for _, name := range header {
+		for i := 0; i < len(name); i++ {
+			if _, found := columns[name[:i]]; name[i] == '.' && found {
+				return fmt.Errorf("%w: %s and %s", ErrColumn, name[:i], name)
+			}
+		}
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Comma = comma
+	writer.Write(header)
+	for _, row := range rows {
+		record := make([]string, len(header))
+		for name, cell := range row {
+			record[columns[name]] = cell
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func flattenRow(obj map[string]interface{}, prefix string, row map[string]string, addColumn func(string)) error {
+	for _, key := range sortedKeys(obj) {
+		name := prefix + key
+		if v, ok := obj[key].(map[string]interface{}); ok && len(v) > 0 {
+			if err := flattenRow(v, name+".", row, addColumn); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, found := row[name]; found {
+			return fmt.Errorf("%w: %s", ErrColumn, name)
+		}
+		switch v := obj[key].(type) {
+		case map[string]interface{}:
+			row[name] = "{}"
+		case string:
+			row[name] = v
+		default:
+			row[name] = marshal(v)
+		}
+		addColumn(name)
+	}
+	return nil
+}
+
+// readTable builds an array of objects from CSV rows, using the first row as
+// the header. Cells holding numbers, booleans, null or JSON arrays and objects
+// are converted back into those types; empty cells are left out.
+func readTable(r io.Reader, comma rune) (interface{}, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	if comma == '\t' {
+		reader.LazyQuotes = true
+	}
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, ErrEmpty
+	}
+	if err != nil {
+		return nil, err
+	}
+	paths := make([][]interface{}, len(header))
+	for i, name := range header {
+		for _, part := range strings.Split(name, ".") {
+			paths[i] = append(paths[i], part)
+		}
+	}
+
+	result := make([]interface{}, 0)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var obj interface{} = make(map[string]interface{})
+		for i, cell := range record {
+			if i < len(paths) && cell != "" {
+				if obj, err = assignPath(obj, paths[i], inferCell(cell)); err != nil {
+					return nil, err
+				}
+			}
+		}
+		result = append(result, obj)
+	}
+	return result, nil
+}
+
+func inferCell(cell string) interface{} {
+	if strings.TrimSpace(cell) != cell {
+		return cell
+	}
+	value, err := parseValue(cell)
+	if err != nil {
+		return cell
+	}
+	if _, ok := value.(string); ok {
+		return cell
+	}
+	return value
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTable(t *testing.T) {
+	withNumbers(t)
+	testCases := []struct {
+		input, table, output string
+	}{
+		{`[{"a": 1, "b": {"c": "x", "d": [1, 2]}}, {"e": {}, "a": null}]`,
+			"a,b.c,b.d,e\n1,x,\"[1,2]\",\nnull,,,{}\n",
+			`[{"a":1,"b":{"c":"x","d":[1,2]}},{"a":null,"e":{}}]`},
+		// empty strings are left out, and strings holding JSON text are read
+		// back as the values they hold
+		{`[{"a": "", "b": "1", "c": " 1", "d": "\"q\""}]`,
+			"a,b,c,d\n,1,\" 1\",\"\"\"q\"\"\"\n",
+			`[{"b":1,"c":" 1","d":"\"q\""}]`},
+	}
+	for _, c := range testCases {
+		value, err := parse(c.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var table strings.Builder
+		if err := writeTable(&table, value, ','); err != nil {
+			t.Errorf("%s: %v", c.input, err)
+			continue
+		}
+		if got := table.String(); got != c.table {
+			t.Errorf("%s: want: %q - got: %q", c.input, c.table, got)
+		}
+		read, err := readTable(strings.NewReader(table.String()), ',')
+		if err != nil {
+			t.Errorf("%q: %v", table.String(), err)
+		} else if got := marshal(read); got != c.output {
+			t.Errorf("%q: want: %s - got: %s", table.String(), c.output, got)
+		}
+	}
+
+	for input, want := range map[string]error{
+		`[{"a.b": 1, "a": {"b": 2}}]`:         ErrColumn,
+		`[{"a": {"b.c": 1, "b": {"c": 2}}}]`:  ErrColumn,
+		`[{"a": 1, "a.b": 2}]`:                ErrColumn,
+		`[{"a": {}}, {"a": {"b": {"c": 1}}}]`: ErrColumn,
+		`[{"x": {"a": null}}, {"x.a.b": 1}]`:  ErrColumn,
+		`{"a": 1}`:                            ErrTable,
+		`[1]`:                                 ErrTable,
+	} {
+		value, err := parse(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := writeTable(&strings.Builder{}, value, ','); !errors.Is(err, want) {
+			t.Errorf("%s: want: %v - got: %v", input, want, err)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// marshal encodes a parsed value back into compact JSON, with object keys in
+// sorted order
+func marshal(value interface{}) string {
+	var sb strings.Builder
+	writeValue(&sb, value)
+	return sb.String()
+}
+
+func writeValue(sb *strings.Builder, value interface{}) {
+	switch v := value.(type) {
+	case nil:
+		sb.WriteString("null")
+	case bool:
+		sb.WriteString(strconv.FormatBool(v))
+	case float64:
+		sb.WriteString(formatFloat(v))
+	case Number:
+		sb.WriteString(string(v))
+	case string:
+		sb.WriteString(quote(v))
+	case []interface{}:
+		sb.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			writeValue(sb, item)
+		}
+		sb.WriteByte(']')
+	case map[string]interface{}:
+		sb.WriteByte('{')
+		for i, key := range sortedKeys(v) {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			sb.WriteString(quote(key))
+			sb.WriteByte(':')
+			writeValue(sb, v[key])
+		}
+		sb.WriteByte('}')
+	}
+}
+
+func formatFloat(f float64) string {
+	if f == math.Trunc(f) && math.Abs(f) < 1e21 {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// quote returns s as a JSON string literal, escaping only what is required
+func quote(s string) string {
+	const hex = "0123456789abcdef"
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, c := range s {
+		switch c {
+		case '"', '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(c)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\f':
+			sb.WriteString(`\f`)
+		default:
+			if c < 0x20 {
+				sb.WriteString(`\u00`)
+				sb.WriteByte(hex[c>>4])
+				sb.WriteByte(hex[c&0xf])
+			} else {
+				sb.WriteRune(c)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// marshalIndent is like marshal, but places each array element and object
+// member on its own line, indented by one copy of indent per nesting level
+func marshalIndent(value interface{}, indent string) string {
+	var sb strings.Builder
+	writeValueIndent(&sb, value, indent, "\n")
+	return sb.String()
+}
+
+func writeValueIndent(sb *strings.Builder, value interface{}, indent, newline string) {
+	switch v := value.(type) {
+	case []interface{}:
+		if len(v) == 0 {
+			sb.WriteString("[]")
+			return
+		}
+		sb.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			sb.WriteString(newline + indent)
+			writeValueIndent(sb, item, indent, newline+indent)
+		}
+		sb.WriteString(newline + "]")
+	case map[string]interface{}:
+		if len(v) == 0 {
+			sb.WriteString("{}")
+			return
+		}
+		sb.WriteByte('{')
+		for i, key := range sortedKeys(v) {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			sb.WriteString(newline + indent)
+			sb.WriteString(quote(key))
+			sb.WriteString(": ")
+			writeValueIndent(sb, v[key], indent, newline+indent)
+		}
+		sb.WriteString(newline + "}")
+	default:
+		writeValue(sb, value)
+	}
+}
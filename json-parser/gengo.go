@@ -0,0 +1,293 @@
+package main
+
+import (
+	"fmt"
+	"go/format"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+const (
+	kindUnknown byte = iota
+	kindNull
+	kindBool
+	kindInt
+	kindFloat
+	kindString
+	kindObject
+	kindArray
+	kindMixed
+)
+
+// goType is the type inferred for a value, merged across all samples seen at
+// the same position in the documents
+type goType struct {
+	kind     byte
+	nullable bool
+	count    int
+	fields   map[string]*goField
+	elem     *goType
+}
+
+type goField struct {
+	count int
+	typ   *goType
+}
+
+var commonInitialisms = map[string]bool{
+	"API": true, "ASCII": true, "CPU": true, "CSS": true, "DNS": true, "EOF": true,
+	"GUID": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true, "IP": true,
+	"JSON": true, "RAM": true, "SQL": true, "SSH": true, "TCP": true, "TLS": true,
+	"TTL": true, "UDP": true, "UI": true, "UID": true, "URI": true, "URL": true,
+	"UTF8": true, "UUID": true, "XML": true,
+}
+
+func generateGoTypes(filenames []string) {
+	useNumber = true
+	root := &goType{}
+	if len(filenames) == 0 {
+		filenames = []string{"-"}
+	}
+	for _, name := range filenames {
+		file := os.Stdin
+		if name != "-" {
+			var err error
+			file, err = os.Open(name)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			panic(err)
+		}
+		result, err := parse(string(data))
+		checkParseError(err)
+		root.merge(result)
+	}
+
+	source, err := goSource(root, packageName, typeName)
+	if err != nil {
+		panic(err)
+	}
+	os.Stdout.Write(source)
+}
+
+func (t *goType) merge(value interface{}) {
+	t.count++
+	switch v := value.(type) {
+	case nil:
+		t.nullable = true
+		if t.kind == kindUnknown {
+			t.kind = kindNull
+		}
+	case bool:
+		t.mergeKind(kindBool)
+	case float64:
+		t.mergeKind(kindFloat)
+	case Number:
+		if v.IsInt() {
+			t.mergeKind(kindInt)
+		} else {
+			t.mergeKind(kindFloat)
+		}
+	case string:
+		t.mergeKind(kindString)
+	case []interface{}:
+		if t.mergeKind(kindArray) {
+			if t.elem == nil {
+				t.elem = &goType{}
+			}
+			for _, item := range v {
+				t.elem.merge(item)
+			}
+		}
+	case map[string]interface{}:
+		if t.mergeKind(kindObject) {
+			if t.fields == nil {
+				t.fields = make(map[string]*goField)
+			}
+			for key, item := range v {
+				field, ok := t.fields[key]
+				if !ok {
+					field = &goField{typ: &goType{}}
+					t.fields[key] = field
+				}
+				field.count++
+				field.typ.merge(item)
+			}
+		}
+	}
+}
+
+// mergeKind combines the current kind with a newly seen one, returning false
+// when the result can no longer be described by a concrete type
+func (t *goType) mergeKind(kind byte) bool {
+	switch {
+	case t.kind == kindUnknown || t.kind == kindNull:
+		t.kind = kind
+	case t.kind == kind:
+	case t.kind == kindInt && kind == kindFloat, t.kind == kindFloat && kind == kindInt:
+		t.kind = kindFloat
+	default:
+		t.kind = kindMixed
+		t.fields = nil
+		t.elem = nil
+	}
+	return t.kind != kindMixed
+}
+
+type goGenerator struct {
+	out   strings.Builder
+	names map[string]bool
+	queue []namedType
+}
+
+type namedType struct {
+	name string
+	typ  *goType
+}
+
+func goSource(root *goType, pkg, name string) ([]byte, error) {
+	g := &goGenerator{names: make(map[string]bool)}
+	fmt.Fprintf(&g.out, "package %s\n", pkg)
+	name = g.uniqueName(goName(name))
+	if root.kind == kindObject {
+		g.queue = append(g.queue, namedType{name, root})
+	} else {
+		fmt.Fprintf(&g.out, "\ntype %s %s\n", name, g.typeExpr(root, name, false))
+	}
+	for len(g.queue) > 0 {
+		next := g.queue[0]
+		g.queue = g.queue[1:]
+		g.writeStruct(next.name, next.typ)
+	}
+	return format.Source([]byte(g.out.String()))
+}
+
+func (g *goGenerator) writeStruct(name string, t *goType) {
+	keys := make([]string, 0, len(t.fields))
+	for key := range t.fields {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	used := make(map[string]bool)
+	fmt.Fprintf(&g.out, "\ntype %s struct {\n", name)
+	for _, key := range keys {
+		if !validTagName(key) {
+			fmt.Fprintf(&g.out, "\t// %s left out, a struct tag can't name it\n", quote(key))
+			continue
+		}
+		field := t.fields[key]
+		fieldName := goName(key)
+		for i := 2; used[fieldName]; i++ {
+			fieldName = fmt.Sprintf("%s%d", goName(key), i)
+		}
+		used[fieldName] = true
+		optional := field.count < t.count || field.typ.nullable
+		tag := key
+		if optional {
+			tag += ",omitempty"
+		} else if key == "-" {
+			// a tag of only - leaves the field out
+			tag += ","
+		}
+		fmt.Fprintf(&g.out, "\t%s %s `json:%q`\n", fieldName, g.typeExpr(field.typ, name+fieldName, optional), tag)
+	}
+	fmt.Fprintf(&g.out, "}\n")
+}
+
+func (g *goGenerator) typeExpr(t *goType, name string, optional bool) string {
+	var expr string
+	switch t.kind {
+	case kindBool:
+		expr = "bool"
+	case kindInt:
+		expr = "int64"
+	case kindFloat:
+		expr = "float64"
+	case kindString:
+		expr = "string"
+	case kindObject:
+		expr = g.uniqueName(name)
+		g.queue = append(g.queue, namedType{expr, t})
+	case kindArray:
+		elemName := name + "Item"
+		if strings.HasSuffix(name, "s") && len(name) > 1 && !strings.HasSuffix(name, "ss") && !strings.HasSuffix(name, "us") {
+			elemName = strings.TrimSuffix(name, "s")
+		}
+		return "[]" + g.typeExpr(t.elem, elemName, t.elem.nullable && t.elem.kind != kindNull)
+	default:
+		return "interface{}"
+	}
+	if optional {
+		expr = "*" + expr
+	}
+	return expr
+}
+
+func (g *goGenerator) uniqueName(name string) string {
+	unique := name
+	for i := 2; g.names[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+	g.names[unique] = true
+	return unique
+}
+
+// validTagName tells if encoding/json can match key with the name of a struct
+// tag, which can't be empty and allows only some punctuation
+func validTagName(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// goName converts a JSON key into an exported Go identifier
+func goName(key string) string {
+	words := strings.FieldsFunc(key, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var sb strings.Builder
+	for _, word := range words {
+		for _, part := range splitCamelCase(word) {
+			upper := strings.ToUpper(part)
+			if commonInitialisms[upper] {
+				sb.WriteString(upper)
+			} else {
+				runes := []rune(part)
+				runes[0] = unicode.ToUpper(runes[0])
+				sb.WriteString(string(runes))
+			}
+		}
+	}
+	name := sb.String()
+	if name == "" || !unicode.IsLetter([]rune(name)[0]) {
+		name = "Field" + name
+	}
+	return name
+}
+
+func splitCamelCase(word string) (parts []string) {
+	runes := []rune(word)
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		if unicode.IsUpper(runes[i]) && !unicode.IsUpper(runes[i-1]) {
+			parts = append(parts, string(runes[start:i]))
+			start = i
+		}
+	}
+	return append(parts, string(runes[start:]))
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestGoSource(t *testing.T) {
+	withNumbers(t)
+	testCases := []struct {
+		documents []string
+		want      string
+	}{
+		{[]string{`{"id": 1, "user": {"name": "a", "address": {"city": "x"}}, "status": "ok",
+			"tags": ["a", "b"], "mixed": [1, "two", null], "nums": [1, 2.5], "items": [{"a": 1}, {"a": 2, "b": null}],
+			"": 1, "-": true, "a,b": 2, "Address": [{"y": 1}], "status_list": [1]}`}, golden(t, "nested")},
+		{[]string{`[{"a": 1, "list": [true, null]}, {"a": 1.5, "b": {"c": []}}]`}, golden(t, "array")},
+		{[]string{`[1]`, `{"a": 1}`}, "package main\n\ntype Root interface{}\n"},
+		{[]string{`{"Status": [{"s": "-"}], "class": [1], "-": 1}`, `{"Status": []}`}, golden(t, "keys")},
+	}
+	for _, c := range testCases {
+		root := &goType{}
+		for _, document := range c.documents {
+			value, err := parse(document)
+			if err != nil {
+				t.Fatal(err)
+			}
+			root.merge(value)
+		}
+		source, err := goSource(root, "main", "Root")
+		if err != nil {
+			t.Errorf("%v: %v", c.documents, err)
+		} else if got := string(source); got != c.want {
+			t.Errorf("%v: want:\n%s\ngot:\n%s", c.documents, c.want, got)
+		}
+	}
+}
+
+func golden(t *testing.T, name string) string {
+	data, err := os.ReadFile("testdata/gengo_" + name + ".go.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var ErrGron = errors.New("invalid gron statement")
+var ErrIndex = errors.New("array index too far past the end")
+
+// maxIndexGap bounds the elements left out before an index assigned past the
+// end of an array, which are filled with null
+const maxIndexGap = 1 << 20
+
+// gron writes one assignment per value, so the document can be searched and
+// compared with line based tools, e.g. json.users[0].name = "x";
+func gron(w io.Writer, value interface{}, path string) {
+	switch v := value.(type) {
+	case []interface{}:
+		fmt.Fprintf(w, "%s = [];\n", path)
+		for i, item := range v {
+			gron(w, item, fmt.Sprintf("%s[%d]", path, i))
+		}
+	case map[string]interface{}:
+		fmt.Fprintf(w, "%s = {};\n", path)
+		for _, key := range sortedKeys(v) {
+			gron(w, v[key], path+pathKey(key))
+		}
+	default:
+		fmt.Fprintf(w, "%s = %s;\n", path, marshal(v))
+	}
+}
+
+// ungron rebuilds a document from the statements written by gron. Statements
+// may be missing or out of order, e.g. after being filtered by grep.
+func ungron(r io.Reader) (result interface{}, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		path, value, err := parseGronStatement(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		result, err = assignPath(result, path, value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	if lineNumber == 0 {
+		return nil, ErrEmpty
+	}
+	return
+}
+
+// parseGronStatement splits a statement into its path, made of string keys
+// and int indexes, and the value being assigned
+func parseGronStatement(line string) (path []interface{}, value interface{}, err error) {
+	i := 0
+	for i < len(line) && isIdentifierChar(line[i], i > 0) {
+		i++
+	}
+	if i == 0 {
+		return nil, nil, ErrGron
+	}
+	for i < len(line) && line[i] != ' ' && line[i] != '=' {
+		switch {
+		case line[i] == '.':
+			start := i + 1
+			i++
+			for i < len(line) && isIdentifierChar(line[i], i > start) {
+				i++
+			}
+			if i == start {
+				return nil, nil, ErrGron
+			}
+			path = append(path, line[start:i])
+		case strings.HasPrefix(line[i:], `["`):
+			start := i + 1
+			i += 2
+			for i < len(line) && line[i] != '"' {
+				if line[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i+1 >= len(line) || line[i+1] != ']' {
+				return nil, nil, ErrGron
+			}
+			key, err := parseValue(line[start : i+1])
+			if err != nil {
+				return nil, nil, err
+			}
+			path = append(path, key)
+			i += 2
+		case line[i] == '[':
+			end := strings.IndexByte(line[i:], ']')
+			if end < 0 {
+				return nil, nil, ErrGron
+			}
+			index, err := strconv.Atoi(line[i+1 : i+end])
+			if err != nil || index < 0 {
+				return nil, nil, ErrGron
+			}
+			path = append(path, index)
+			i += end + 1
+		default:
+			return nil, nil, ErrGron
+		}
+	}
+	rest := strings.TrimSpace(line[i:])
+	if !strings.HasPrefix(rest, "=") {
+		return nil, nil, ErrGron
+	}
+	rest = strings.TrimSuffix(strings.TrimSpace(rest[1:]), ";")
+	value, err = parseValue(rest)
+	return
+}
+
+func isIdentifierChar(c byte, digitAllowed bool) bool {
+	return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || digitAllowed && c >= '0' && c <= '9'
+}
+
+func assignPath(container interface{}, path []interface{}, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		// keep what was already assigned under an empty object or array
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if _, ok := container.(map[string]interface{}); ok && len(v) == 0 {
+				return container, nil
+			}
+		case []interface{}:
+			if _, ok := container.([]interface{}); ok && len(v) == 0 {
+				return container, nil
+			}
+		}
+		return value, nil
+	}
+	var err error
+	switch key := path[0].(type) {
+	case string:
+		obj, ok := container.(map[string]interface{})
+		if !ok {
+			obj = make(map[string]interface{})
+		}
+		obj[key], err = assignPath(obj[key], path[1:], value)
+		return obj, err
+	case int:
+		arr, ok := container.([]interface{})
+		if !ok {
+			arr = make([]interface{}, 0)
+		}
+		if key-len(arr) > maxIndexGap {
+			return nil, fmt.Errorf("%w: %d", ErrIndex, key)
+		}
+		for len(arr) <= key {
+			arr = append(arr, nil)
+		}
+		arr[key], err = assignPath(arr[key], path[1:], value)
+		return arr, err
+	}
+	return container, nil
+}
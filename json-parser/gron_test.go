@@ -0,0 +1,34 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestUngron(t *testing.T) {
+	withNumbers(t)
+	testCases := map[string]string{
+		"json = {};\njson.a = [];\njson.a[0] = 1;\njson.a[1] = \"x\";\n": `{"a":[1,"x"]}`,
+		"json.b[\"c d\"] = true;\njson.a = {};\n":                        `{"a":{},"b":{"c d":true}}`,
+		"json[2] = null;\njson[0] = 1;\n":                                `[1,null,null]`,
+	}
+	for input, want := range testCases {
+		if value, err := ungron(strings.NewReader(input)); err != nil {
+			t.Errorf("%q: %v", input, err)
+		} else if got := marshal(value); got != want {
+			t.Errorf("%q: want: %s - got: %s", input, want, got)
+		}
+	}
+	for input, want := range map[string]error{
+		"json[999999999] = 1;\n":             ErrIndex,
+		"json = [];\njson.a[9999999] = 1;\n": ErrIndex,
+		"json.a = ;\n":                       ErrEmpty,
+		"json[-1] = 1;\n":                    ErrGron,
+		"json..a = 1;\n":                     ErrGron,
+	} {
+		if _, err := ungron(strings.NewReader(input)); !errors.Is(err, want) {
+			t.Errorf("%q: want: %v - got: %v", input, want, err)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bufio"
 	"errors"
 	"flag"
 	"fmt"
@@ -8,15 +9,62 @@ import (
 	"math"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
-var payloadOnly bool
-var depth, maxDepth int
+var payloadOnly, relaxed, useNumber, genGo, showStats, gronMode, ungronMode, toCSV, fromCSV, tabSeparated bool
+var toMsgPack, fromMsgPack, toCBOR, fromCBOR, cborDiag, inPlace, each, showAST, useTape, replMode, toYAML, fromYAML, lintMode, jsonOutput bool
+var depth, top int
+var maxDepth = math.MaxInt
+var maxBody int64
+var timeout time.Duration
+var typeName, packageName, setPointer, insertPointer, deletePointer, editValue, pointer, lintConfigFile, serveAddr string
 
 func main() {
 
 	flag.BoolVar(&payloadOnly, "payload-only", false, "Check if type is object or array")
 	flag.IntVar(&maxDepth, "max-depth", math.MaxInt, "Max nesting depth of objects")
+	flag.BoolVar(&relaxed, "relaxed", false, "Allow // and /* */ comments")
+	flag.BoolVar(&genGo, "gen-go", false, "Generate Go type definitions from one or more sample documents")
+	flag.StringVar(&typeName, "type-name", "Root", "Name of the root type generated by -gen-go")
+	flag.StringVar(&packageName, "package", "main", "Package name of the source generated by -gen-go")
+	flag.BoolVar(&showStats, "stats", false, "Report statistics about the document instead of printing it")
+	flag.IntVar(&top, "top", 10, "Number of entries listed in the -stats rankings")
+	flag.BoolVar(&gronMode, "gron", false, "Flatten the document into one path = value assignment per line")
+	flag.BoolVar(&ungronMode, "ungron", false, "Rebuild a document from the assignments written by -gron")
+	flag.BoolVar(&toCSV, "to-csv", false, "Convert an array of objects into CSV rows")
+	flag.BoolVar(&fromCSV, "from-csv", false, "Convert CSV rows into an array of objects")
+	flag.BoolVar(&toYAML, "to-yaml", false, "Convert into YAML in block style")
+	flag.BoolVar(&fromYAML, "from-yaml", false, "Convert YAML into JSON, one document per line when there are several")
+	flag.BoolVar(&tabSeparated, "tsv", false, "Use tabs instead of commas with -to-csv and -from-csv")
+	flag.BoolVar(&toMsgPack, "to-msgpack", false, "Convert the document into MessagePack")
+	flag.BoolVar(&fromMsgPack, "from-msgpack", false, "Convert MessagePack data into JSON")
+	flag.BoolVar(&toCBOR, "to-cbor", false, "Convert the document into CBOR")
+	flag.BoolVar(&fromCBOR, "from-cbor", false, "Convert CBOR data into JSON")
+	flag.BoolVar(&cborDiag, "cbor-diag", false, "Print CBOR data in diagnostic notation")
+	flag.StringVar(&setPointer, "set", "", "Replace or add the value at this JSON Pointer, keeping the rest of the text as is")
+	flag.StringVar(&insertPointer, "insert", "", "Insert a value before the array element at this JSON Pointer")
+	flag.StringVar(&deletePointer, "delete", "", "Remove the value at this JSON Pointer, keeping the comments around it")
+	flag.StringVar(&editValue, "value", "null", "JSON value used by -set and -insert")
+	flag.BoolVar(&inPlace, "in-place", false, "Write the result of -set, -insert or -delete back to the file")
+	flag.BoolVar(&each, "each", false, "Print each element of the array as a compact JSON line, reading one element at a time")
+	flag.StringVar(&pointer, "pointer", "", "JSON Pointer to the array used by -each, or to the value to print")
+	flag.BoolVar(&useTape, "tape", false, "Parse with the two pass tape backend instead of recursive descent")
+	flag.BoolVar(&showAST, "ast", false, "Print the syntax tree with the line and column where each value starts and ends")
+	flag.BoolVar(&replMode, "repl", false, "Explore the document of the file given interactively, with cd, ls, cat and find")
+	flag.BoolVar(&lintMode, "lint", false, "Check the document against the lint rules, reporting the position of each finding")
+	flag.StringVar(&lintConfigFile, "lint-config", "", "JSON file enabling, disabling and configuring each lint rule")
+	flag.BoolVar(&jsonOutput, "json", false, "Write the value as indented JSON text, with the keys of objects in order, instead of Go syntax")
+	flag.Var(transformFlag{build: dropNulls, boolean: true}, "drop-nulls", "Transform: remove the members with a null value")
+	flag.Var(transformFlag{build: dropKeys}, "drop-keys", "Transform: remove the members with a key matching a glob `pattern`")
+	flag.Var(transformFlag{build: redact}, "redact", "Transform: replace the values at a JSON `pointer`, * matching any key or index")
+	flag.Var(transformFlag{build: sortArrays, boolean: true}, "sort-arrays", "Transform: sort the arrays of scalars")
+	flag.Var(transformFlag{build: sortBy}, "sort-by", "Transform: sort the arrays of objects by the member with the given `key`")
+	flag.Var(transformFlag{build: unique, boolean: true}, "unique", "Transform: remove repeated elements from arrays")
+	flag.StringVar(&serveAddr, "serve", "", "Serve /validate, /format and /query over HTTP on this `address`, e.g. :8080")
+	flag.Int64Var(&maxBody, "max-body", 1<<20, "Largest request body accepted by -serve, in bytes")
+	flag.DurationVar(&timeout, "timeout", 10*time.Second, "Time allowed to -serve each request")
 	flag.Parse()
 
 	if !flag.Parsed() {
@@ -24,10 +72,41 @@ func main() {
 		os.Exit(1)
 	}
 
+	if top < 0 {
+		fmt.Fprintln(os.Stderr, "-top must be 0 or more")
+		os.Exit(1)
+	}
+
+	editOp := ""
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "set", "insert", "delete":
+			if editOp != "" {
+				fmt.Fprintf(os.Stderr, "-%s and -%s can't be used together\n", editOp, f.Name)
+				os.Exit(1)
+			}
+			editOp = f.Name
+		}
+	})
+
+	if genGo {
+		generateGoTypes(flag.Args())
+		return
+	}
+
+	if serveAddr != "" {
+		useNumber = true
+		if err := serve(serveAddr, maxBody, timeout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	var file *os.File
 	var err error
 
-	if flag.NArg() == 0 {
+	if flag.NArg() == 0 && !replMode {
 		file = os.Stdin
 	} else if flag.NArg() == 1 {
 		file, err = os.Open(flag.Arg(0))
@@ -39,15 +118,227 @@ func main() {
 		os.Exit(1)
 	}
 
+	if gronMode || ungronMode || toCSV || fromCSV || toMsgPack || toCBOR || each || replMode || toYAML || fromYAML || jsonOutput {
+		useNumber = true
+	}
+
+	comma := ','
+	if tabSeparated {
+		comma = '\t'
+	}
+
+	if fromYAML {
+		documents, err := parseYAML(file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		for _, document := range documents {
+			fmt.Println(marshalIndent(applyTransforms(document), "  "))
+		}
+		return
+	}
+
+	if fromCSV {
+		result, err := readTable(file, comma)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println(marshalIndent(applyTransforms(result), "  "))
+		return
+	}
+
+	if each {
+		it, err := newArrayIterator(file, pointer)
+		if err == nil {
+			output := bufio.NewWriter(os.Stdout)
+			for it.Next() {
+				output.WriteString(marshal(applyTransforms(it.Value())))
+				output.WriteByte('\n')
+			}
+			output.Flush()
+			err = it.Err()
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if ungronMode {
+		result, err := ungron(file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println(marshalIndent(applyTransforms(result), "  "))
+		return
+	}
+
 	data, err := io.ReadAll(file)
 	if err != nil {
 		panic(err)
 	}
 
+	if showAST {
+		node, err := parseAST(string(data))
+		checkParseError(err)
+		printAST(os.Stdout, node, "")
+		return
+	}
+
+	if lintMode {
+		config, err := loadLintConfig(lintConfigFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		_, err = parse(string(data))
+		checkParseError(err)
+		findings, err := lint(string(data), config)
+		checkParseError(err)
+		filename := flag.Arg(0)
+		if filename == "" {
+			filename = "-"
+		}
+		printLintFindings(os.Stdout, filename, findings)
+		if len(findings) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if replMode {
+		result, err := parse(string(data))
+		checkParseError(err)
+		runREPL(result, os.Stdin, os.Stdout)
+		return
+	}
+
+	if pointer != "" {
+		var result interface{}
+		if useTape {
+			var t *tape
+			t, err = buildTape(string(data))
+			if err == nil && payloadOnly && t.root().kind() != '{' && t.root().kind() != '[' {
+				err = ErrPayload
+			}
+			checkParseError(err)
+			result, err = t.lookupValue(pointer)
+		} else {
+			result, err = parse(string(data))
+			checkParseError(err)
+			result, err = lookupPointer(result, pointer)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println(marshalIndent(applyTransforms(result), "  "))
+		return
+	}
+
+	if editOp != "" {
+		doc, err := parseCST(string(data))
+		checkParseError(err)
+		var edited string
+		switch editOp {
+		case "set":
+			edited, err = doc.set(setPointer, editValue)
+		case "insert":
+			edited, err = doc.insert(insertPointer, editValue)
+		case "delete":
+			edited, err = doc.delete(deletePointer)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if inPlace && file != os.Stdin {
+			err = os.WriteFile(file.Name(), []byte(edited), 0644)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		} else {
+			fmt.Print(edited)
+		}
+		return
+	}
+
+	if fromMsgPack || fromCBOR || cborDiag {
+		var result interface{}
+		var diag string
+		switch {
+		case fromMsgPack:
+			result, err = decodeMsgPack(data)
+		case fromCBOR:
+			result, err = decodeCBOR(data)
+		default:
+			diag, err = cborDiagnostic(data)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if cborDiag {
+			fmt.Println(diag)
+		} else {
+			fmt.Println(marshalIndent(applyTransforms(result), "  "))
+		}
+		return
+	}
+
 	result, err := parse(string(data))
+	checkParseError(err)
+	result = applyTransforms(result)
+
+	if gronMode {
+		gron(os.Stdout, result, "json")
+		return
+	}
+
+	if toMsgPack {
+		os.Stdout.Write(encodeMsgPack(result))
+		return
+	}
+
+	if toCBOR {
+		os.Stdout.Write(encodeCBOR(result))
+		return
+	}
+
+	if toYAML {
+		fmt.Print(marshalYAML(result))
+		return
+	}
+
+	if toCSV {
+		if err := writeTable(os.Stdout, result, comma); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if showStats {
+		collectStats(result).print(os.Stdout, top)
+		return
+	}
+
+	if jsonOutput {
+		fmt.Println(marshalIndent(result, "  "))
+		return
+	}
+
+	fmt.Printf("%#v\n", result)
+}
+
+func checkParseError(err error) {
 	switch err {
 	case nil:
-		fmt.Printf("%#v\n", result)
 	case ErrArray, ErrKeyWord, ErrObject, ErrString, ErrNumber, ErrToken, ErrEmpty, ErrPayload, ErrMaxDepth:
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
@@ -56,10 +347,30 @@ func main() {
 	}
 }
 
+// Token also keeps its position in the data, Start being the offset of its
+// first byte and End the offset just after its last byte
 type Token struct {
-	Type    byte
-	Value   float64
-	Content string
+	Type       byte
+	Value      float64
+	Content    string
+	Start, End int
+}
+
+// Number keeps the original literal of a number, returned by parse instead of
+// float64 when useNumber is set
+type Number string
+
+func (n Number) Float64() (float64, error) {
+	return strconv.ParseFloat(string(n), 64)
+}
+
+func (n Number) Int64() (int64, error) {
+	return strconv.ParseInt(string(n), 10, 64)
+}
+
+func (n Number) IsInt() bool {
+	_, err := n.Int64()
+	return err == nil
 }
 
 var ErrEmpty = errors.New("no data")
@@ -75,6 +386,7 @@ var ErrMaxDepth = errors.New("max depth reached")
 func tokenize(data string) (tokens []*Token, err error) {
 	var tokenType byte
 	var escaping, unicode bool
+	var start int
 	unicodeDigits := []rune{}
 	content := []rune{}
 	for i, c := range data {
@@ -89,14 +401,37 @@ func tokenize(data string) (tokens []*Token, err error) {
 					continue
 				case '"':
 					tokenType = 'S'
+					start = i
 				case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
-					content = append(content, c)
+					// handled as number below, so a single digit at the end is not lost
 					tokenType = '0'
+					start = i
+					retry = true
 				case '[', ']', '{', '}', ',', ':':
-					tokens = append(tokens, &Token{byte(c), 0, ""})
+					tokens = append(tokens, &Token{byte(c), 0, "", i, i + 1})
 				default:
-					content = append(content, c)
+					if relaxed && strings.HasPrefix(data[i:], "//") {
+						tokenType = '/'
+						continue
+					}
+					if relaxed && strings.HasPrefix(data[i:], "/*") {
+						tokenType = '#'
+						start = i
+						continue
+					}
 					tokenType = '*'
+					start = i
+					retry = true
+				}
+
+			case '/':
+				if c == '\n' {
+					tokenType = 0
+				}
+
+			case '#':
+				if c == '/' && i >= start+3 && data[i-1] == '*' {
+					tokenType = 0
 				}
 
 			case 'S':
@@ -151,27 +486,27 @@ func tokenize(data string) (tokens []*Token, err error) {
 
 				switch c {
 				case '"':
-					tokens = append(tokens, &Token{tokenType, 0, string(content)})
+					tokens = append(tokens, &Token{tokenType, 0, string(content), start, i + 1})
 					content = content[:0]
 					tokenType = 0
 
 				case '\\':
 					escaping = true
 
-				case '\r', '\n', '\t', '\b':
-					return nil, ErrString
-
 				default:
+					if c < 0x20 {
+						return nil, ErrString
+					}
 					content = append(content, c)
 				}
 
 			case '0':
-				parseNumber := false
+				parseNumber, consumed := false, true
 				switch c {
 				case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9', 'e', 'E', '.', '+':
 					content = append(content, c)
 				default:
-					parseNumber = true
+					parseNumber, consumed = true, false
 				}
 
 				if i == len(data)-1 {
@@ -179,26 +514,29 @@ func tokenize(data string) (tokens []*Token, err error) {
 				}
 
 				if parseNumber {
-					if len(content) > 1 && content[0] == '0' && content[1] != '.' {
-						// No leading zero
+					if !validNumber(string(content)) {
 						return nil, ErrNumber
 					}
 					value, err := strconv.ParseFloat(string(content), 64)
 					if err != nil {
 						return nil, ErrNumber
 					}
-					tokens = append(tokens, &Token{tokenType, value, ""})
+					end := i
+					if consumed {
+						end++
+					}
+					tokens = append(tokens, &Token{tokenType, value, string(content), start, end})
 					content = content[:0]
 					tokenType = 0
-					retry = i < len(data)
+					retry = !consumed
 				}
 
 			case '*':
-				parseKeyword := false
+				parseKeyword, consumed := false, true
 				if c >= 'a' && c <= 'z' {
 					content = append(content, c)
 				} else {
-					parseKeyword = true
+					parseKeyword, consumed = true, false
 				}
 
 				if i == len(data)-1 {
@@ -216,18 +554,55 @@ func tokenize(data string) (tokens []*Token, err error) {
 					default:
 						return nil, ErrKeyWord
 					}
-					tokens = append(tokens, &Token{tokenType, 0, ""})
+					end := i
+					if consumed {
+						end++
+					}
+					tokens = append(tokens, &Token{tokenType, 0, "", start, end})
 					content = content[:0]
 					tokenType = 0
-					retry = i < len(data)
+					retry = !consumed
 				}
 			}
 		}
 	}
+	if tokenType == '#' {
+		// unterminated comment
+		return nil, ErrToken
+	}
+	if tokenType == 'S' {
+		return nil, ErrString
+	}
 	return tokens, nil
 }
 
 func parse(data string) (result interface{}, err error) {
+	result, err = parseValue(data)
+	if err != nil {
+		return
+	}
+	if payloadOnly {
+		switch result.(type) {
+		case []interface{}:
+			// ok
+		case map[string]interface{}:
+			// ok
+		default:
+			return nil, ErrPayload
+		}
+	}
+	return
+}
+
+// parseValue parses a single value of any type, regardless of payloadOnly
+func parseValue(data string) (result interface{}, err error) {
+	if useTape {
+		t, err := buildTape(data)
+		if err != nil {
+			return nil, err
+		}
+		return t.root().value(), nil
+	}
 	tokens, err := tokenize(data)
 	if err != nil {
 		return
@@ -242,16 +617,6 @@ func parse(data string) (result interface{}, err error) {
 	if end < len(tokens) {
 		return nil, ErrToken
 	}
-	if payloadOnly {
-		switch result.(type) {
-		case []interface{}:
-			// ok
-		case map[string]interface{}:
-			// ok
-		default:
-			return nil, ErrPayload
-		}
-	}
 	return
 }
 
@@ -271,6 +636,9 @@ func parseTokens(tokens []*Token, start int) (result interface{}, end int, err e
 	case 'S':
 		return tokens[start].Content, start + 1, nil
 	case '0':
+		if useNumber {
+			return Number(tokens[start].Content), start + 1, nil
+		}
 		return tokens[start].Value, start + 1, nil
 	case 'n':
 		return nil, start + 1, nil
@@ -288,7 +656,6 @@ func parseArray(tokens []*Token, start int) (result interface{}, end int, err er
 	i := start + 1
 	for i < len(tokens) {
 		if tokens[i].Type == ']' {
-			end = i + 1
 			break
 		}
 		var value interface{}
@@ -302,13 +669,14 @@ func parseArray(tokens []*Token, start int) (result interface{}, end int, err er
 			if i < len(tokens) && tokens[i].Type == ']' {
 				return nil, 0, ErrArray
 			}
+		} else {
+			break
 		}
 	}
 	if i >= len(tokens) || tokens[i].Type != ']' {
 		return nil, 0, ErrArray
 	}
-	result = arr
-	return
+	return arr, i + 1, nil
 }
 
 func parseObject(tokens []*Token, start int) (result interface{}, end int, err error) {
@@ -316,7 +684,6 @@ func parseObject(tokens []*Token, start int) (result interface{}, end int, err e
 	i := start + 1
 	for i < len(tokens) {
 		if tokens[i].Type == '}' {
-			end = i + 1
 			break
 		}
 		var key string
@@ -326,10 +693,13 @@ func parseObject(tokens []*Token, start int) (result interface{}, end int, err e
 		}
 		key = tokens[i].Content
 		i++
-		if tokens[i].Type != ':' {
+		if i >= len(tokens) || tokens[i].Type != ':' {
 			return nil, 0, ErrObject
 		}
 		i++
+		if i >= len(tokens) {
+			return nil, 0, ErrObject
+		}
 		value, i, err = parseTokens(tokens, i)
 		if err != nil {
 			return nil, 0, err
@@ -340,11 +710,12 @@ func parseObject(tokens []*Token, start int) (result interface{}, end int, err e
 			if i < len(tokens) && tokens[i].Type == '}' {
 				return nil, 0, ErrObject
 			}
+		} else {
+			break
 		}
 	}
 	if i >= len(tokens) || tokens[i].Type != '}' {
 		return nil, 0, ErrObject
 	}
-	result = obj
-	return
+	return obj, i + 1, nil
 }
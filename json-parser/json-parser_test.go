@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestTokenizeAtEnd covers numbers and keywords ended by the end of the data
+// rather than by a character read again as the next token
+func TestTokenizeAtEnd(t *testing.T) {
+	testCases := map[string][]string{
+		"1":        {"1"},
+		"[1":       {"[", "1"},
+		"[1]":      {"[", "1", "]"},
+		"-2.5e3":   {"-2.5e3"},
+		"[true":    {"[", "true"},
+		"[null,1":  {"[", "null", ",", "1"},
+		"{\"a\":0": {"{", `"a"`, ":", "0"},
+	}
+	for input, want := range testCases {
+		tokens, err := tokenize(input)
+		if err != nil {
+			t.Errorf("%q: %v", input, err)
+			continue
+		}
+		if len(tokens) != len(want) {
+			t.Errorf("%q: want: %d tokens - got: %d", input, len(want), len(tokens))
+			continue
+		}
+		for i, token := range tokens {
+			if got := input[token.Start:token.End]; got != want[i] {
+				t.Errorf("%q: token %d: want: %q - got: %q", input, i, want[i], got)
+			}
+		}
+	}
+
+	for input, want := range map[string]error{"1": nil, "true": nil, "[1": ErrArray, "[true": ErrArray, "{\"a\":0": ErrObject} {
+		for _, backend := range backends {
+			saved := useTape
+			useTape = backend.tape
+			_, err := parse(input)
+			useTape = saved
+			if !errors.Is(err, want) {
+				t.Errorf("%s: %q: want: %v - got: %v", backend.name, input, want, err)
+			}
+		}
+	}
+}
@@ -1,5 +1,5 @@
 #!/bin/bash
-go build -o json-parser.exe json-parser.go
+go build -o json-parser.exe .
 if [ $? -ne 0 ] ; then
     exit
 fi
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+)
+
+// lineEditor reads lines with history and tab completion when the input is a
+// terminal, switching it to raw mode with stty. Other inputs, and terminals
+// where stty fails or is not used, as on Windows, are read one plain line at a
+// time.
+type lineEditor struct {
+	reader   *bufio.Reader
+	output   io.Writer
+	complete func(string) []string
+	history  []string
+	terminal string
+	// afterReturn is set after a \r, so that the \n of a \r\n doesn't end
+	// another line
+	afterReturn bool
+}
+
+func newLineEditor(input io.Reader, output io.Writer, complete func(string) []string) *lineEditor {
+	e := &lineEditor{reader: bufio.NewReader(input), output: output, complete: complete}
+	if input == os.Stdin && runtime.GOOS != "windows" {
+		if state, err := stty("-g"); err == nil {
+			if _, err := stty("raw", "-echo"); err == nil {
+				e.terminal = strings.TrimSpace(state)
+			}
+		}
+	}
+	return e
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	output, err := cmd.Output()
+	return string(output), err
+}
+
+func (e *lineEditor) close() {
+	if e.terminal != "" {
+		stty(e.terminal)
+	}
+}
+
+// Write makes the output usable in raw mode, where line feeds don't return the
+// cursor to the first column
+func (e *lineEditor) Write(p []byte) (int, error) {
+	if e.terminal == "" {
+		return e.output.Write(p)
+	}
+	_, err := io.WriteString(e.output, strings.ReplaceAll(string(p), "\n", "\r\n"))
+	return len(p), err
+}
+
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	fmt.Fprint(e.output, prompt)
+	if e.terminal == "" {
+		line, err := e.reader.ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	line := []rune{}
+	cursor := 0
+	historyIndex := len(e.history)
+	redraw := func() {
+		fmt.Fprintf(e.output, "\r\x1b[K%s%s", prompt, string(line))
+		if back := len(line) - cursor; back > 0 {
+			fmt.Fprintf(e.output, "\x1b[%dD", back)
+		}
+	}
+	for {
+		c, _, err := e.reader.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		if c == '\n' && e.afterReturn {
+			e.afterReturn = false
+			continue
+		}
+		e.afterReturn = c == '\r'
+		switch c {
+		case '\r', '\n':
+			fmt.Fprint(e.output, "\r\n")
+			text := string(line)
+			if strings.TrimSpace(text) != "" && (len(e.history) == 0 || e.history[len(e.history)-1] != text) {
+				e.history = append(e.history, text)
+			}
+			return text, nil
+		case 3: // Ctrl-C
+			fmt.Fprint(e.output, "^C\r\n")
+			line, cursor = line[:0], 0
+			fmt.Fprint(e.output, prompt)
+			continue
+		case 4: // Ctrl-D
+			if len(line) == 0 {
+				fmt.Fprint(e.output, "\r\n")
+				return "", io.EOF
+			}
+		case 1: // Ctrl-A
+			cursor = 0
+		case 5: // Ctrl-E
+			cursor = len(line)
+		case 127, 8: // Backspace
+			if cursor > 0 {
+				line = append(line[:cursor-1], line[cursor:]...)
+				cursor--
+			}
+		case '\t':
+			line, cursor = e.completeLine(prompt, line, cursor)
+		case 27: // escape sequences for the arrow keys
+			if next, _ := e.reader.ReadByte(); next != '[' {
+				continue
+			}
+			key, _ := e.reader.ReadByte()
+			switch key {
+			case 'A', 'B':
+				if key == 'A' && historyIndex > 0 {
+					historyIndex--
+				} else if key == 'B' && historyIndex < len(e.history) {
+					historyIndex++
+				}
+				line = line[:0]
+				if historyIndex < len(e.history) {
+					line = []rune(e.history[historyIndex])
+				}
+				cursor = len(line)
+			case 'C':
+				cursor = min(cursor+1, len(line))
+			case 'D':
+				cursor = max(cursor-1, 0)
+			}
+		default:
+			if c >= ' ' {
+				line = append(line[:cursor], append([]rune{c}, line[cursor:]...)...)
+				cursor++
+			}
+		}
+		redraw()
+	}
+}
+
+// completeLine extends the line up to the cursor with the common prefix of all
+// completions, listing them when there are several
+func (e *lineEditor) completeLine(prompt string, line []rune, cursor int) ([]rune, int) {
+	matches := e.complete(string(line[:cursor]))
+	if len(matches) == 0 {
+		return line, cursor
+	}
+	prefix := matches[0]
+	for _, match := range matches[1:] {
+		for !strings.HasPrefix(match, prefix) {
+			_, size := utf8.DecodeLastRuneInString(prefix)
+			prefix = prefix[:len(prefix)-size]
+		}
+	}
+	if len(matches) > 1 && len(prefix) <= len(string(line[:cursor])) {
+		fmt.Fprint(e.output, "\r\n")
+		for _, match := range matches {
+			_, name, _ := strings.Cut(match, " ")
+			if i := strings.LastIndexByte(name, '/'); i >= 0 {
+				name = name[i+1:]
+			}
+			if name == "" {
+				name = match
+			}
+			fmt.Fprintf(e.output, "%s  ", name)
+		}
+		fmt.Fprint(e.output, "\r\n")
+	}
+	completed := append([]rune(prefix), line[cursor:]...)
+	return completed, len([]rune(prefix))
+}
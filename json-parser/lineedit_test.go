@@ -0,0 +1,55 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLineEditorRaw(t *testing.T) {
+	input := "ls\r\ncd /a\rcat x\x7fy\x01\x1b[C\x1b[Cz\r\x1b[A\r"
+	e := newLineEditor(strings.NewReader(input), io.Discard, nil)
+	// as if stty had switched a terminal to raw mode
+	e.terminal = "sane"
+	want := []string{"ls", "cd /a", "cazt y", "cazt y"}
+	for _, line := range want {
+		got, err := e.readLine("> ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != line {
+			t.Errorf("want: %q - got: %q", line, got)
+		}
+	}
+	if _, err := e.readLine("> "); err != io.EOF {
+		t.Errorf("want: %v - got: %v", io.EOF, err)
+	}
+}
+
+func TestCompleteLine(t *testing.T) {
+	testCases := []struct {
+		line    string
+		matches []string
+		want    string
+	}{
+		{"cd /na", []string{"cd /name"}, "cd /name"},
+		{"cd ", []string{"cd /é1", "cd /é2"}, "cd /é"},
+		{"cd /", []string{"cd /é", "cd /è"}, "cd /"},
+		{"cat 日", []string{"cat 日本", "cat 日曜"}, "cat 日"},
+		{"ls", nil, "ls"},
+	}
+	for _, c := range testCases {
+		var output strings.Builder
+		e := newLineEditor(strings.NewReader(""), &output, func(string) []string {
+			return c.matches
+		})
+		line := []rune(c.line + " rest")
+		completed, cursor := e.completeLine("> ", line, len([]rune(c.line)))
+		if got := string(completed[:cursor]); got != c.want {
+			t.Errorf("%q: want: %q - got: %q", c.line, c.want, got)
+		}
+		if got := string(completed[cursor:]); got != " rest" {
+			t.Errorf("%q: the rest of the line changed to %q", c.line, got)
+		}
+	}
+}
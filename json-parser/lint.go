@@ -0,0 +1,282 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var ErrLintConfig = errors.New("invalid lint configuration")
+
+var lintRules = []string{
+	"canonical-escapes",
+	"duplicate-keys",
+	"forbidden-keys",
+	"key-naming",
+	"max-nesting",
+	"number-precision",
+	"trailing-whitespace",
+}
+
+var keyStyles = map[string]*regexp.Regexp{
+	"camelCase":  regexp.MustCompile(`^[a-z][a-zA-Z0-9]*$`),
+	"PascalCase": regexp.MustCompile(`^[A-Z][a-zA-Z0-9]*$`),
+	"snake_case": regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`),
+	"kebab-case": regexp.MustCompile(`^[a-z][a-z0-9]*(-[a-z0-9]+)*$`),
+}
+
+// lintConfig tells which rules are enabled and holds their options. The rules
+// needing options to make sense, key-naming and forbidden-keys, are disabled
+// unless configured.
+type lintConfig struct {
+	enabled    map[string]bool
+	keyStyle   string
+	maxNesting int
+	forbidden  []string
+}
+
+func defaultLintConfig() *lintConfig {
+	config := &lintConfig{enabled: map[string]bool{}, keyStyle: "camelCase", maxNesting: 32}
+	for _, rule := range lintRules {
+		config.enabled[rule] = rule != "key-naming" && rule != "forbidden-keys"
+	}
+	return config
+}
+
+// loadLintConfig reads a JSON object with a member per rule, either true or
+// false or an object with its options and "enabled", which defaults to true.
+// Forbidden keys are patterns matched regardless of case.
+//
+//	{"key-naming": {"style": "snake_case"}, "max-nesting": {"max": 8},
+//	 "forbidden-keys": {"keys": ["password", "*_secret"]}, "trailing-whitespace": false}
+func loadLintConfig(filename string) (*lintConfig, error) {
+	config := defaultLintConfig()
+	if filename == "" {
+		return config, nil
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	value, err := parseValue(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	rules, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: %w: expected an object", filename, ErrLintConfig)
+	}
+	for rule, setting := range rules {
+		if !slices.Contains(lintRules, rule) {
+			return nil, fmt.Errorf("%s: %w: unknown rule %s", filename, ErrLintConfig, rule)
+		}
+		switch s := setting.(type) {
+		case bool:
+			config.enabled[rule] = s
+		case map[string]interface{}:
+			config.enabled[rule] = s["enabled"] != false
+			if err := config.setOptions(rule, s); err != nil {
+				return nil, fmt.Errorf("%s: %w: %s: %v", filename, ErrLintConfig, rule, err)
+			}
+		default:
+			return nil, fmt.Errorf("%s: %w: %s must be a boolean or an object", filename, ErrLintConfig, rule)
+		}
+	}
+	return config, nil
+}
+
+func (config *lintConfig) setOptions(rule string, options map[string]interface{}) error {
+	for name, option := range options {
+		switch {
+		case name == "enabled":
+		case rule == "key-naming" && name == "style":
+			style, _ := option.(string)
+			if keyStyles[style] == nil {
+				return fmt.Errorf("unknown style %v", option)
+			}
+			config.keyStyle = style
+		case rule == "max-nesting" && name == "max":
+			max := 0.0
+			switch option.(type) {
+			case float64, Number:
+				max = numberValue(option)
+			}
+			if max < 1 || max != float64(int(max)) {
+				return fmt.Errorf("max must be a positive integer")
+			}
+			config.maxNesting = int(max)
+		case rule == "forbidden-keys" && name == "keys":
+			keys, ok := option.([]interface{})
+			if !ok {
+				return fmt.Errorf("keys must be an array of strings")
+			}
+			config.forbidden = config.forbidden[:0]
+			for _, key := range keys {
+				pattern, ok := key.(string)
+				if _, err := path.Match(pattern, ""); !ok || err != nil {
+					return fmt.Errorf("invalid key pattern %v", key)
+				}
+				config.forbidden = append(config.forbidden, pattern)
+			}
+		default:
+			return fmt.Errorf("unknown option %s", name)
+		}
+	}
+	return nil
+}
+
+type lintFinding struct {
+	Position
+	rule, message string
+}
+
+// lint checks a valid document against the enabled rules, returning the
+// findings in the order of their positions
+func lint(data string, config *lintConfig) ([]lintFinding, error) {
+	tokens, err := tokenize(data)
+	if err != nil {
+		return nil, err
+	}
+	index := newLineIndex(data)
+	var findings []lintFinding
+	report := func(offset int, rule, format string, args ...interface{}) {
+		if config.enabled[rule] {
+			findings = append(findings, lintFinding{index.position(offset), rule, fmt.Sprintf(format, args...)})
+		}
+	}
+
+	// each open container has the offsets of the keys seen, nil for arrays
+	var open []map[string]int
+	for i, token := range tokens {
+		switch token.Type {
+		case '{', '[':
+			if len(open) == config.maxNesting {
+				report(token.Start, "max-nesting", "nesting deeper than %d levels", config.maxNesting)
+			}
+			var keys map[string]int
+			if token.Type == '{' {
+				keys = map[string]int{}
+			}
+			open = append(open, keys)
+		case '}', ']':
+			open = open[:len(open)-1]
+		case 'S':
+			lintEscapes(data[token.Start:token.End], token.Start, report)
+			if len(open) == 0 || open[len(open)-1] == nil || i+1 >= len(tokens) || tokens[i+1].Type != ':' {
+				break
+			}
+			key := token.Content
+			keys := open[len(open)-1]
+			if first, found := keys[key]; found {
+				report(token.Start, "duplicate-keys", "duplicate key %s, first at %v", quote(key), index.position(first))
+			} else {
+				keys[key] = token.Start
+			}
+			if !keyStyles[config.keyStyle].MatchString(key) {
+				report(token.Start, "key-naming", "key %s is not %s", quote(key), config.keyStyle)
+			}
+			for _, pattern := range config.forbidden {
+				if matched, _ := path.Match(strings.ToLower(pattern), strings.ToLower(key)); matched {
+					report(token.Start, "forbidden-keys", "key %s is forbidden", quote(key))
+					break
+				}
+			}
+		case '0':
+			if message := precisionLoss(token.Content); message != "" {
+				report(token.Start, "number-precision", "%s", message)
+			}
+		}
+	}
+
+	for offset := 0; offset < len(data); {
+		end := strings.IndexByte(data[offset:], '\n')
+		if end < 0 {
+			end = len(data)
+		} else {
+			end += offset
+		}
+		line := strings.TrimSuffix(data[offset:end], "\r")
+		if trimmed := strings.TrimRight(line, " \t"); len(trimmed) < len(line) {
+			report(offset+len(trimmed), "trailing-whitespace", "trailing whitespace")
+		}
+		offset = end + 1
+	}
+
+	slices.SortStableFunc(findings, func(a, b lintFinding) int {
+		return a.Offset - b.Offset
+	})
+	return findings, nil
+}
+
+// lintEscapes reports the escapes of a string literal other than the ones of
+// the canonical form of RFC 8785: \" \\ \b \f \n \r \t and \u00xx in lower case
+// for the other control characters, everything else written as is
+func lintEscapes(literal string, start int, report func(int, string, string, ...interface{})) {
+	for i := 1; i < len(literal)-1; i++ {
+		if literal[i] != '\\' {
+			continue
+		}
+		escape := literal[i : i+2]
+		switch literal[i+1] {
+		case '/':
+			report(start+i, "canonical-escapes", `non-canonical escape \/, write / instead`)
+		case 'u':
+			escape = literal[i : i+6]
+			code, _ := strconv.ParseUint(escape[2:], 16, 32)
+			canonical := ""
+			switch {
+			case code >= 0xd800 && code < 0xdc00 && strings.HasPrefix(literal[i+6:], `\u`):
+				low, _ := strconv.ParseUint(literal[i+8:i+12], 16, 32)
+				if low >= 0xdc00 && low < 0xe000 {
+					escape = literal[i : i+12]
+					canonical = string(rune(0x10000 + (code-0xd800)<<10 + low - 0xdc00))
+				}
+			case code >= 0xd800 && code < 0xe000:
+				// lone surrogates can only be written escaped
+			case code >= 0x20 || shortEscapes[byte(code)] != "":
+				canonical = quote(string(rune(code)))
+				canonical = canonical[1 : len(canonical)-1]
+			case escape != strings.ToLower(escape):
+				canonical = strings.ToLower(escape)
+			}
+			if canonical != "" {
+				report(start+i, "canonical-escapes", "non-canonical escape %s, write %s instead", escape, canonical)
+			}
+		}
+		i += len(escape) - 1
+	}
+}
+
+var shortEscapes = map[byte]string{'\b': `\b`, '\f': `\f`, '\n': `\n`, '\r': `\r`, '\t': `\t`}
+
+// precisionLoss describes how a number changes when read as a float64, which
+// is what most parsers do, or returns an empty string when it doesn't
+func precisionLoss(literal string) string {
+	f, err := strconv.ParseFloat(literal, 64)
+	if err != nil {
+		return fmt.Sprintf("number %s is out of the range of float64", literal)
+	}
+	exact, ok := new(big.Rat).SetString(literal)
+	if !ok {
+		return ""
+	}
+	shortest := strconv.FormatFloat(f, 'g', -1, 64)
+	rounded, _ := new(big.Rat).SetString(shortest)
+	if exact.Cmp(rounded) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("number %s is read as %s", literal, formatFloat(f))
+}
+
+func printLintFindings(w io.Writer, filename string, findings []lintFinding) {
+	for _, finding := range findings {
+		fmt.Fprintf(w, "%s:%v: %s (%s)\n", filename, finding.Position, finding.message, finding.rule)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	config := defaultLintConfig()
+	config.enabled["key-naming"] = true
+	config.enabled["forbidden-keys"] = true
+	config.forbidden = []string{"*secret"}
+	config.maxNesting = 2
+	data := "{\"a\": 1, \"b_c\": [[0.1]], \n \"a\": \"\\/\\u00e9\\n\", \"apiSecret\": 9007199254740993}"
+	want := []string{
+		"1:10 key-naming",
+		"1:18 max-nesting",
+		"1:25 trailing-whitespace",
+		"2:2 duplicate-keys",
+		"2:8 canonical-escapes",
+		"2:10 canonical-escapes",
+		"2:21 forbidden-keys",
+		"2:34 number-precision",
+	}
+	findings, err := lint(data, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, finding := range findings {
+		got = append(got, fmt.Sprintf("%v %s", finding.Position, finding.rule))
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("want: %v - got: %v", want, got)
+	}
+}
+
+func TestLoadLintConfig(t *testing.T) {
+	saved := useNumber
+	defer func() {
+		useNumber = saved
+	}()
+	for _, numbers := range []bool{false, true} {
+		useNumber = numbers
+		filename := filepath.Join(t.TempDir(), "lint.json")
+		for setting, want := range map[string]int{`8`: 8, `1e1`: 10, `0`: -1, `1.5`: -1, `"8"`: -1} {
+			config := `{"max-nesting": {"max": ` + setting + `}}`
+			if err := os.WriteFile(filename, []byte(config), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := loadLintConfig(filename)
+			switch {
+			case want < 0 && !errors.Is(err, ErrLintConfig):
+				t.Errorf("numbers %t: %s: want: %v - got: %v", numbers, config, ErrLintConfig, err)
+			case want > 0 && err != nil:
+				t.Errorf("numbers %t: %s: %v", numbers, config, err)
+			case want > 0 && loaded.maxNesting != want:
+				t.Errorf("numbers %t: %s: want: %d - got: %d", numbers, config, want, loaded.maxNesting)
+			}
+		}
+	}
+}
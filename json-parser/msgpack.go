@@ -0,0 +1,323 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+)
+
+var ErrMsgPack = errors.New("invalid msgpack data")
+
+const (
+	numberInt byte = iota
+	numberUint
+	numberFloat
+)
+
+// splitNumber classifies a parsed number as a signed or unsigned integer when
+// it can be represented exactly as one, or as a float otherwise
+func splitNumber(value interface{}) (i int64, u uint64, f float64, kind byte) {
+	switch v := value.(type) {
+	case float64:
+		if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+			return int64(v), 0, v, numberInt
+		}
+		return 0, 0, v, numberFloat
+	case Number:
+		var err error
+		if i, err = v.Int64(); err == nil {
+			return i, 0, float64(i), numberInt
+		}
+		if u, err = strconv.ParseUint(string(v), 10, 64); err == nil {
+			return 0, u, float64(u), numberUint
+		}
+		f, _ = v.Float64()
+		return 0, 0, f, numberFloat
+	}
+	return
+}
+
+func encodeMsgPack(value interface{}) []byte {
+	return appendMsgPack(nil, value)
+}
+
+func appendMsgPack(out []byte, value interface{}) []byte {
+	switch v := value.(type) {
+	case nil:
+		return append(out, 0xc0)
+	case bool:
+		if v {
+			return append(out, 0xc3)
+		}
+		return append(out, 0xc2)
+	case float64, Number:
+		i, u, f, kind := splitNumber(v)
+		switch {
+		case kind == numberUint:
+			return binary.BigEndian.AppendUint64(append(out, 0xcf), u)
+		case kind == numberFloat && float64(float32(f)) == f:
+			return binary.BigEndian.AppendUint32(append(out, 0xca), math.Float32bits(float32(f)))
+		case kind == numberFloat:
+			return binary.BigEndian.AppendUint64(append(out, 0xcb), math.Float64bits(f))
+		case i >= 0 && i <= 0x7f, i < 0 && i >= -32:
+			return append(out, byte(i))
+		case i > 0 && i <= math.MaxUint8:
+			return append(out, 0xcc, byte(i))
+		case i > 0 && i <= math.MaxUint16:
+			return binary.BigEndian.AppendUint16(append(out, 0xcd), uint16(i))
+		case i > 0 && i <= math.MaxUint32:
+			return binary.BigEndian.AppendUint32(append(out, 0xce), uint32(i))
+		case i > 0:
+			return binary.BigEndian.AppendUint64(append(out, 0xcf), uint64(i))
+		case i >= math.MinInt8:
+			return append(out, 0xd0, byte(i))
+		case i >= math.MinInt16:
+			return binary.BigEndian.AppendUint16(append(out, 0xd1), uint16(i))
+		case i >= math.MinInt32:
+			return binary.BigEndian.AppendUint32(append(out, 0xd2), uint32(i))
+		default:
+			return binary.BigEndian.AppendUint64(append(out, 0xd3), uint64(i))
+		}
+	case string:
+		out = appendMsgPackHeader(out, len(v), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		return append(out, v...)
+	case []interface{}:
+		out = appendMsgPackHeader(out, len(v), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, item := range v {
+			out = appendMsgPack(out, item)
+		}
+		return out
+	case map[string]interface{}:
+		out = appendMsgPackHeader(out, len(v), 0x80, 16, 0, 0xde, 0xdf)
+		for _, key := range sortedKeys(v) {
+			out = appendMsgPack(out, key)
+			out = appendMsgPack(out, v[key])
+		}
+		return out
+	}
+	return out
+}
+
+// appendMsgPackHeader writes the shortest header for a string, array or map of
+// the given length, a fix prefix only being used below fixLimit
+func appendMsgPackHeader(out []byte, length int, fix byte, fixLimit int, code8, code16, code32 byte) []byte {
+	switch {
+	case length < fixLimit:
+		return append(out, fix|byte(length))
+	case length <= math.MaxUint8 && code8 != 0:
+		return append(out, code8, byte(length))
+	case length <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(out, code16), uint16(length))
+	default:
+		return binary.BigEndian.AppendUint32(append(out, code32), uint32(length))
+	}
+}
+
+type msgPackDecoder struct {
+	data []byte
+	pos  int
+}
+
+// decodeMsgPack converts a single msgpack value into the parser's value model.
+// Binary and extension data become base64 strings, except for timestamps that
+// are formatted as RFC 3339.
+func decodeMsgPack(data []byte) (result interface{}, err error) {
+	if len(data) == 0 {
+		return nil, ErrEmpty
+	}
+	d := &msgPackDecoder{data: data}
+	result, err = d.decode()
+	if err == nil && d.pos < len(data) {
+		err = ErrMsgPack
+	}
+	return
+}
+
+func (d *msgPackDecoder) read(n int) ([]byte, error) {
+	if n < 0 || len(d.data)-d.pos < n {
+		return nil, ErrMsgPack
+	}
+	d.pos += n
+	return d.data[d.pos-n : d.pos], nil
+}
+
+func (d *msgPackDecoder) uint(n int) (uint64, error) {
+	b, err := d.read(n)
+	if err != nil {
+		return 0, err
+	}
+	var u uint64
+	for _, c := range b {
+		u = u<<8 | uint64(c)
+	}
+	return u, nil
+}
+
+func (d *msgPackDecoder) decode() (result interface{}, err error) {
+	depth++
+	defer func() {
+		depth--
+	}()
+	if depth > maxDepth {
+		return nil, ErrMaxDepth
+	}
+
+	code, err := d.read(1)
+	if err != nil {
+		return nil, err
+	}
+	c := code[0]
+	switch {
+	case c <= 0x7f:
+		return Number(strconv.Itoa(int(c))), nil
+	case c >= 0xe0:
+		return Number(strconv.Itoa(int(int8(c)))), nil
+	case c&0xf0 == 0x80:
+		return d.decodeMap(int(c & 0x0f))
+	case c&0xf0 == 0x90:
+		return d.decodeArray(int(c & 0x0f))
+	case c&0xe0 == 0xa0:
+		return d.decodeString(int(c & 0x1f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.uint(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.read(int(n))
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.EncodeToString(b), nil
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.uint(1 << (c - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeExt(int(n))
+	case 0xca:
+		u, err := d.uint(4)
+		return msgPackFloat(float64(math.Float32frombits(uint32(u)))), err
+	case 0xcb:
+		u, err := d.uint(8)
+		return msgPackFloat(math.Float64frombits(u)), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, err := d.uint(1 << (c - 0xcc))
+		return Number(strconv.FormatUint(u, 10)), err
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (c - 0xd0)
+		u, err := d.uint(size)
+		// sign extend from the size read
+		shift := 64 - 8*size
+		return Number(strconv.FormatInt(int64(u<<shift)>>shift, 10)), err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.decodeExt(1 << (c - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.uint(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeString(int(n))
+	case 0xdc, 0xdd:
+		n, err := d.uint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(int(n))
+	case 0xde, 0xdf:
+		n, err := d.uint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(int(n))
+	}
+	return nil, ErrMsgPack
+}
+
+func msgPackFloat(f float64) interface{} {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil
+	}
+	return f
+}
+
+func (d *msgPackDecoder) decodeString(n int) (interface{}, error) {
+	b, err := d.read(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (d *msgPackDecoder) decodeArray(n int) (interface{}, error) {
+	if n > len(d.data)-d.pos {
+		return nil, ErrMsgPack
+	}
+	arr := make([]interface{}, 0, n)
+	for range n {
+		item, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, item)
+	}
+	return arr, nil
+}
+
+func (d *msgPackDecoder) decodeMap(n int) (interface{}, error) {
+	if n > len(d.data)-d.pos {
+		return nil, ErrMsgPack
+	}
+	obj := make(map[string]interface{}, n)
+	for range n {
+		key, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		value, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		if s, ok := key.(string); ok {
+			obj[s] = value
+		} else {
+			obj[marshal(key)] = value
+		}
+	}
+	return obj, nil
+}
+
+func (d *msgPackDecoder) decodeExt(n int) (interface{}, error) {
+	b, err := d.read(n + 1)
+	if err != nil {
+		return nil, err
+	}
+	extType, b := int8(b[0]), b[1:]
+	if extType == -1 {
+		var t time.Time
+		switch len(b) {
+		case 4:
+			t = time.Unix(int64(binary.BigEndian.Uint32(b)), 0)
+		case 8:
+			u := binary.BigEndian.Uint64(b)
+			t = time.Unix(int64(u&0x3ffffffff), int64(u>>34))
+		case 12:
+			t = time.Unix(int64(binary.BigEndian.Uint64(b[4:])), int64(binary.BigEndian.Uint32(b)))
+		default:
+			return nil, ErrMsgPack
+		}
+		return t.UTC().Format(time.RFC3339Nano), nil
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// TestMsgPackEncode checks the shortest format of the msgpack specification is
+// used at the limits of each one
+func TestMsgPackEncode(t *testing.T) {
+	withNumbers(t)
+	testCases := map[string]string{
+		`null`:                              "c0",
+		`false`:                             "c2",
+		`true`:                              "c3",
+		`0`:                                 "00",
+		`127`:                               "7f",
+		`128`:                               "cc80",
+		`255`:                               "ccff",
+		`256`:                               "cd0100",
+		`65536`:                             "ce00010000",
+		`4294967296`:                        "cf0000000100000000",
+		`18446744073709551615`:              "cfffffffffffffffff",
+		`-1`:                                "ff",
+		`-32`:                               "e0",
+		`-33`:                               "d0df",
+		`-128`:                              "d080",
+		`-129`:                              "d1ff7f",
+		`-32769`:                            "d2ffff7fff",
+		`-2147483649`:                       "d3ffffffff7fffffff",
+		`1.5`:                               "ca3fc00000",
+		`1.1`:                               "cb3ff199999999999a",
+		`""`:                                "a0",
+		`"a"`:                               "a161",
+		`"` + strings.Repeat("x", 31) + `"`: "bf" + strings.Repeat("78", 31),
+		`"` + strings.Repeat("x", 32) + `"`: "d920" + strings.Repeat("78", 32),
+		`[]`:                                "90",
+		`[1, [2]]`:                          "92019102",
+		`[0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0]`: "dc0010" + strings.Repeat("00", 16),
+		`{}`:                                "80",
+		`{"b": 1, "a": {"c": null}}`:        "82a16181a163c0a16201",
+	}
+	for input, want := range testCases {
+		value, err := parse(input)
+		if err != nil {
+			t.Errorf("%s: %v", input, err)
+		} else if got := hex.EncodeToString(encodeMsgPack(value)); got != want {
+			t.Errorf("%s: want: %s - got: %s", input, want, got)
+		}
+	}
+}
+
+func TestMsgPackDecode(t *testing.T) {
+	testCases := map[string]string{
+		"d0ff":                           `-1`,
+		"d1ff7f":                         `-129`,
+		"d3ffffffff7fffffff":             `-2147483649`,
+		"cfffffffffffffffff":             `18446744073709551615`,
+		"cb7ff8000000000000":             `null`,
+		"c403010203":                     `"AQID"`,
+		"d4010a":                         `"Cg=="`,
+		"d6ff00000000":                   `"1970-01-01T00:00:00Z"`,
+		"d7ff0000000400000001":           `"1970-01-01T00:00:01.000000001Z"`,
+		"c70cff000000010000000000000002": `"1970-01-01T00:00:02.000000001Z"`,
+		"8101c3":                         `{"1":true}`,
+		"dd00000001a0":                   `[""]`,
+	}
+	for input, want := range testCases {
+		data, _ := hex.DecodeString(input)
+		if value, err := decodeSafely(decodeMsgPack, data); err != nil {
+			t.Errorf("%s: %v", input, err)
+		} else if got := marshal(value); got != want {
+			t.Errorf("%s: want: %s - got: %s", input, want, got)
+		}
+	}
+}
+
+func TestMsgPackRoundTrip(t *testing.T) {
+	withNumbers(t)
+	documents := []string{
+		`{"a":[1,-2,0.5,"x",null,true,false],"b":{"":""},"c":[]}`,
+		`[18446744073709551615,-9223372036854775808,0.1,-33,300]`,
+		`"\u0000é😀"`,
+	}
+	for _, document := range documents {
+		value, err := parse(document)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded := encodeMsgPack(value)
+		decoded, err := decodeSafely(decodeMsgPack, encoded)
+		if err != nil {
+			t.Errorf("%s: %v", document, err)
+		} else if got, want := marshal(decoded), marshal(value); got != want {
+			t.Errorf("%s: want: %s - got: %s", document, want, got)
+		}
+		for end := 0; end < len(encoded); end++ {
+			if _, err := decodeSafely(decodeMsgPack, encoded[:end]); err == nil || strings.HasPrefix(err.Error(), "panic:") {
+				t.Errorf("%s: %x: want an error - got: %v", document, encoded[:end], err)
+			}
+		}
+	}
+}
+
+func TestMsgPackInvalid(t *testing.T) {
+	for _, input := range []string{
+		"c1",           // never used
+		"0000",         // trailing data
+		"a261",         // short string
+		"dcffff",       // array longer than the data
+		"dfffffffff80", // map longer than the data
+		"d9",           // missing length
+		"c701",         // missing extension type
+		"c703ff010203", // timestamp of a wrong size
+		"c6ffffffff",   // binary longer than the data
+	} {
+		data, _ := hex.DecodeString(input)
+		if _, err := decodeSafely(decodeMsgPack, data); err == nil || strings.HasPrefix(err.Error(), "panic:") {
+			t.Errorf("%s: want an error - got: %v", input, err)
+		}
+	}
+}
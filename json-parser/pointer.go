@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrPointer = errors.New("invalid pointer")
+var ErrNotFound = errors.New("pointer not found")
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its unescaped reference
+// tokens. The empty pointer refers to the whole document.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, ErrPointer
+	}
+	parts := strings.Split(pointer[1:], "/")
+	for i, part := range parts {
+		for j := 0; j < len(part); j++ {
+			if part[j] == '~' && (j+1 == len(part) || part[j+1] != '0' && part[j+1] != '1') {
+				return nil, ErrPointer
+			}
+		}
+		parts[i] = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+	}
+	return parts, nil
+}
+
+func formatPointer(parts []string) string {
+	var sb strings.Builder
+	for _, part := range parts {
+		sb.WriteByte('/')
+		sb.WriteString(strings.ReplaceAll(strings.ReplaceAll(part, "~", "~0"), "/", "~1"))
+	}
+	return sb.String()
+}
+
+// arrayIndex converts a reference token into an array index, which must be
+// made only of digits and without leading zeros
+func arrayIndex(part string) (int, bool) {
+	if part == "" || len(part) > 1 && part[0] == '0' {
+		return 0, false
+	}
+	index := 0
+	for _, c := range part {
+		if c < '0' || c > '9' || index > (1<<31) {
+			return 0, false
+		}
+		index = index*10 + int(c-'0')
+	}
+	return index, true
+}
+
+// lookupPointer returns the value referenced by pointer inside a parsed value
+func lookupPointer(value interface{}, pointer string) (interface{}, error) {
+	parts, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	for _, part := range parts {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			item, ok := v[part]
+			if !ok {
+				return nil, ErrNotFound
+			}
+			value = item
+		case []interface{}:
+			index, ok := arrayIndex(part)
+			if !ok || index >= len(v) {
+				return nil, ErrNotFound
+			}
+			value = v[index]
+		default:
+			return nil, ErrNotFound
+		}
+	}
+	return value, nil
+}
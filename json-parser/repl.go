@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// explorer keeps the state of the interactive mode, the document and the
+// pointer to the value being looked at
+type explorer struct {
+	root    interface{}
+	current []string
+}
+
+var replCommands = []string{"cat", "cd", "exit", "find", "help", "ls", "pointer", "quit"}
+
+func runREPL(root interface{}, input io.Reader, output io.Writer) {
+	e := &explorer{root: root}
+	editor := newLineEditor(input, output, e.complete)
+	defer editor.close()
+	for {
+		line, err := editor.readLine(formatPointer(e.current) + "> ")
+		if err != nil {
+			return
+		}
+		if !e.execute(editor, line) {
+			return
+		}
+	}
+}
+
+// execute runs a single command, returning false when the session is over
+func (e *explorer) execute(w io.Writer, line string) bool {
+	command, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+	arg = strings.TrimSpace(arg)
+	switch command {
+	case "":
+	case "exit", "quit":
+		return false
+	case "help":
+		fmt.Fprintln(w, "cd PATH    change to the value at PATH, absolute or relative, .. for the parent")
+		fmt.Fprintln(w, "ls [PATH]  list the keys or indexes of a value")
+		fmt.Fprintln(w, "cat [PATH] print a value")
+		fmt.Fprintln(w, "find KEY   list the pointers of all members named KEY")
+		fmt.Fprintln(w, "pointer    print the JSON Pointer of the current value")
+		fmt.Fprintln(w, "exit       leave")
+	case "pointer", "pwd":
+		fmt.Fprintln(w, formatPointer(e.current))
+	case "cd":
+		parts, value, err := e.resolve(arg)
+		if err != nil {
+			fmt.Fprintln(w, err)
+			break
+		}
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			e.current = parts
+		default:
+			fmt.Fprintln(w, "not an object or array")
+		}
+	case "ls":
+		_, value, err := e.resolve(arg)
+		if err != nil {
+			fmt.Fprintln(w, err)
+			break
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			for _, key := range sortedKeys(v) {
+				fmt.Fprintf(w, "%-20s %s\n", key, summary(v[key]))
+			}
+		case []interface{}:
+			for i, item := range v {
+				fmt.Fprintf(w, "%-20d %s\n", i, summary(item))
+			}
+		default:
+			fmt.Fprintln(w, summary(v))
+		}
+	case "cat":
+		_, value, err := e.resolve(arg)
+		if err != nil {
+			fmt.Fprintln(w, err)
+			break
+		}
+		fmt.Fprintln(w, marshalIndent(value, "  "))
+	case "find":
+		if arg == "" {
+			fmt.Fprintln(w, "usage: find KEY")
+			break
+		}
+		_, value, _ := e.resolve("")
+		findKey(w, value, slices.Clone(e.current), arg)
+	default:
+		fmt.Fprintf(w, "unknown command: %s (try help)\n", command)
+	}
+	return true
+}
+
+// resolve returns the pointer parts and value for a path relative to the
+// current value, or absolute when starting with /
+func (e *explorer) resolve(path string) ([]string, interface{}, error) {
+	parts := slices.Clone(e.current)
+	if strings.HasPrefix(path, "/") {
+		parts = nil
+		path = path[1:]
+	}
+	if path != "" {
+		for _, part := range strings.Split(path, "/") {
+			switch part {
+			case "", ".":
+			case "..":
+				if len(parts) > 0 {
+					parts = parts[:len(parts)-1]
+				}
+			default:
+				parts = append(parts, strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~"))
+			}
+		}
+	}
+	value, err := lookupPointer(e.root, formatPointer(parts))
+	return parts, value, err
+}
+
+func findKey(w io.Writer, value interface{}, parts []string, key string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, k := range sortedKeys(v) {
+			child := append(parts, k)
+			if k == key {
+				fmt.Fprintf(w, "%s = %s\n", formatPointer(child), summary(v[k]))
+			}
+			findKey(w, v[k], child, key)
+		}
+	case []interface{}:
+		for i, item := range v {
+			findKey(w, item, append(parts, strconv.Itoa(i)), key)
+		}
+	}
+}
+
+// summary describes a value in a single short line
+func summary(value interface{}) string {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return fmt.Sprintf("{...} %d keys", len(v))
+	case []interface{}:
+		return fmt.Sprintf("[...] %d items", len(v))
+	}
+	text := marshal(value)
+	if len(text) > 60 {
+		text = text[:57] + "..."
+	}
+	return text
+}
+
+// complete returns the possible lines completing the word at the end of line:
+// command names for the first word, and keys or indexes of the value the path
+// points to for the argument
+func (e *explorer) complete(line string) []string {
+	command, arg, found := strings.Cut(line, " ")
+	if !found {
+		var matches []string
+		for _, name := range replCommands {
+			if strings.HasPrefix(name, command) {
+				matches = append(matches, name+" ")
+			}
+		}
+		return matches
+	}
+	arg = strings.TrimLeft(arg, " ")
+	dir, prefix := "", arg
+	if i := strings.LastIndexByte(arg, '/'); i >= 0 {
+		dir, prefix = arg[:i+1], arg[i+1:]
+	}
+	_, value, err := e.resolve(dir)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			names = append(names, strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1"))
+		}
+	case []interface{}:
+		for i := range v {
+			names = append(names, strconv.Itoa(i))
+		}
+	}
+	var matches []string
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) {
+			matches = append(matches, command+" "+dir+name)
+		}
+	}
+	return matches
+}
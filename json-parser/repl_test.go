@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const replDocument = `{"a": {"b": [1, {"c": "x"}], "a/b": true, "~": null}, "c": 2}`
+
+func TestREPL(t *testing.T) {
+	root, err := parse(replDocument)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// each line with the prompt it is typed at and what it prints
+	session := []struct{ prompt, line, output string }{
+		{"", "ls", "a                    {...} 3 keys\nc                    2\n"},
+		{"", "cd a", ""},
+		{"/a", "pointer", "/a\n"},
+		{"/a", "ls b", "0                    1\n1                    {...} 1 keys\n"},
+		{"/a", "cd b/1", ""},
+		{"/a/b/1", "pwd", "/a/b/1\n"},
+		{"/a/b/1", "cat", "{\n  \"c\": \"x\"\n}\n"},
+		{"/a/b/1", "cd ..", ""},
+		{"/a/b", "cd ../..", ""},
+		{"", "pointer", "\n"},
+		{"", "find c", "/a/b/1/c = \"x\"\n/c = 2\n"},
+		{"", "cd /a/a~1b", "not an object or array\n"},
+		{"", "cd /x", "pointer not found\n"},
+		{"", "cat /a/~0", "null\n"},
+		{"", "ls /c", "2\n"},
+		{"", "find", "usage: find KEY\n"},
+		{"", "nope", "unknown command: nope (try help)\n"},
+		{"", "", ""},
+		{"", "exit", ""},
+	}
+	var input, want strings.Builder
+	for _, step := range session {
+		input.WriteString(step.line + "\n")
+		want.WriteString(step.prompt + "> " + step.output)
+	}
+	// nothing is read after exit
+	input.WriteString("ls\n")
+	var output strings.Builder
+	runREPL(root, strings.NewReader(input.String()), &output)
+	if got := output.String(); got != want.String() {
+		t.Errorf("want:\n%s\ngot:\n%s", want.String(), got)
+	}
+
+	// the session also ends with the input
+	output.Reset()
+	runREPL(root, strings.NewReader("cd a"), &output)
+	if got := output.String(); got != "> /a> " {
+		t.Errorf("want: %q - got: %q", "> /a> ", got)
+	}
+}
+
+func TestExplorerComplete(t *testing.T) {
+	root, err := parse(replDocument)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		current []string
+		line    string
+		want    []string
+	}{
+		{nil, "c", []string{"cat ", "cd "}},
+		{nil, "p", []string{"pointer "}},
+		{nil, "x", nil},
+		{nil, "cd ", []string{"cd a", "cd c"}},
+		{nil, "ls  a/", []string{"ls a/a~1b", "ls a/b", "ls a/~0"}},
+		{nil, "cat /a/b/", []string{"cat /a/b/0", "cat /a/b/1"}},
+		{nil, "cd a/~", []string{"cd a/~0"}},
+		{nil, "cd x/", nil},
+		{[]string{"a", "b"}, "cd ../a", []string{"cd ../a~1b"}},
+		{[]string{"a", "b"}, "cd 1/", []string{"cd 1/c"}},
+	}
+	for _, c := range testCases {
+		e := &explorer{root: root, current: c.current}
+		if got := e.complete(c.line); strings.Join(got, "|") != strings.Join(c.want, "|") {
+			t.Errorf("%v %q: want: %q - got: %q", c.current, c.line, c.want, got)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// parseMutex serializes parsing in the server, as the parser keeps its state
+// in package variables such as depth
+var parseMutex sync.Mutex
+
+func parseRequest(data string) (interface{}, error) {
+	parseMutex.Lock()
+	defer parseMutex.Unlock()
+	return parse(data)
+}
+
+// newServeHandler returns the handler of the serve mode. Every endpoint takes
+// the document in the body of a POST request and answers with JSON:
+//
+//	/validate            {"valid": true} or {"valid": false, "error": "..."}
+//	/format?indent=N     the document indented with N spaces, compact for 0
+//	/query?pointer=P     the value at the JSON Pointer P
+//
+// Errors other than an invalid document have a status code and
+// {"error": "..."}.
+func newServeHandler(maxBody int64, timeout time.Duration) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/validate", func(w http.ResponseWriter, r *http.Request) {
+		data, ok := readRequest(w, r, maxBody)
+		if !ok {
+			return
+		}
+		if _, err := parseRequest(data); err != nil {
+			writeJSON(w, http.StatusOK, map[string]interface{}{"valid": false, "error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"valid": true})
+	})
+	mux.HandleFunc("/format", func(w http.ResponseWriter, r *http.Request) {
+		indent := 2
+		if arg := r.URL.Query().Get("indent"); arg != "" {
+			var err error
+			if indent, err = strconv.Atoi(arg); err != nil || indent < 0 || indent > 16 {
+				writeError(w, http.StatusBadRequest, "indent must be a number from 0 to 16")
+				return
+			}
+		}
+		data, ok := readRequest(w, r, maxBody)
+		if !ok {
+			return
+		}
+		result, err := parseRequest(data)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if indent == 0 {
+			writeJSON(w, http.StatusOK, result)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, marshalIndent(result, strings.Repeat(" ", indent))+"\n")
+	})
+	mux.HandleFunc("/query", func(w http.ResponseWriter, r *http.Request) {
+		data, ok := readRequest(w, r, maxBody)
+		if !ok {
+			return
+		}
+		result, err := parseRequest(data)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		value, err := lookupPointer(result, r.URL.Query().Get("pointer"))
+		switch {
+		case errors.Is(err, ErrNotFound):
+			writeError(w, http.StatusNotFound, err.Error())
+		case err != nil:
+			writeError(w, http.StatusBadRequest, err.Error())
+		default:
+			writeJSON(w, http.StatusOK, value)
+		}
+	})
+	return http.TimeoutHandler(mux, timeout, `{"error":"timeout"}`)
+}
+
+// readRequest returns the body of a POST request, or writes the error when
+// there is none or it is too large
+func readRequest(w http.ResponseWriter, r *http.Request, maxBody int64) (string, bool) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "use POST with the document as the body")
+		return "", false
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBody))
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("body larger than %d bytes", maxBody))
+		return "", false
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err.Error())
+		return "", false
+	}
+	return string(data), true
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	io.WriteString(w, marshal(value)+"\n")
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]interface{}{"error": message})
+}
+
+// serve runs the server until interrupted, then waits for the requests in
+// progress to finish
+func serve(addr string, maxBody int64, timeout time.Duration) error {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           newServeHandler(maxBody, timeout),
+		ReadHeaderTimeout: timeout,
+		ReadTimeout:       timeout,
+		WriteTimeout:      timeout + time.Second,
+		IdleTimeout:       time.Minute,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+	fmt.Fprintln(os.Stderr, "listening on", addr)
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+	fmt.Fprintln(os.Stderr, "shutting down")
+	shutdown, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return server.Shutdown(shutdown)
+}
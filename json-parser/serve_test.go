@@ -0,0 +1,73 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestServe(t *testing.T) {
+	server := httptest.NewServer(newServeHandler(64, time.Second))
+	defer server.Close()
+
+	testCases := []struct {
+		method, path, body string
+		status             int
+		want               string
+	}{
+		{"POST", "/validate", `{"a": [1, 2]}`, 200, `{"valid":true}`},
+		{"POST", "/validate", `{"a": [1, 2}`, 200, `{"error":"invalid array","valid":false}`},
+		{"POST", "/format?indent=0", ` { "b" : true, "a" : null } `, 200, `{"a":null,"b":true}`},
+		{"POST", "/format", `[1]`, 200, "[\n  1\n]"},
+		{"POST", "/format?indent=x", `[1]`, 400, `{"error":"indent must be a number from 0 to 16"}`},
+		{"POST", "/format", `[1`, 400, `{"error":"invalid array"}`},
+		{"POST", "/query?pointer=/a/1", `{"a": [1, "x"]}`, 200, `"x"`},
+		{"POST", "/query?pointer=/b", `{"a": [1, "x"]}`, 404, `{"error":"pointer not found"}`},
+		{"POST", "/query?pointer=a", `{"a": [1, "x"]}`, 400, `{"error":"invalid pointer"}`},
+		{"GET", "/validate", "", 405, `{"error":"use POST with the document as the body"}`},
+		{"POST", "/validate", "[" + strings.Repeat("1,", 40) + "1]", 413, `{"error":"body larger than 64 bytes"}`},
+	}
+	for _, c := range testCases {
+		request, _ := http.NewRequest(c.method, server.URL+c.path, strings.NewReader(c.body))
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(response.Body)
+		response.Body.Close()
+		if got := strings.TrimSpace(string(body)); response.StatusCode != c.status || got != c.want {
+			t.Errorf("%s %s: want: %d %s - got: %d %s", c.method, c.path, c.status, c.want, response.StatusCode, got)
+		}
+	}
+}
+
+func TestServeLimits(t *testing.T) {
+	saved := maxDepth
+	maxDepth = 3
+	defer func() {
+		maxDepth = saved
+	}()
+	handler := newServeHandler(1024, time.Second)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(nested bool) {
+			defer wg.Done()
+			body, want := `[[1]]`, `{"valid":true}`
+			if nested {
+				body, want = `[[[1]]]`, `{"error":"max depth reached","valid":false}`
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/validate", strings.NewReader(body)))
+			if got := strings.TrimSpace(recorder.Body.String()); got != want {
+				t.Errorf("%s: want: %s - got: %s", body, want, got)
+			}
+		}(i%2 == 0)
+	}
+	wg.Wait()
+}
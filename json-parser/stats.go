@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"unicode/utf8"
+)
+
+type docStats struct {
+	objects, arrays, strings, numbers, booleans, nulls int
+	maxDepth                                           int
+	longestString                                      int
+	longestStringPath                                  string
+	largestArray                                       int
+	largestArrayPath                                   string
+	keys                                               map[string]int
+	paths                                              map[string]*pathStats
+}
+
+// pathStats aggregates the encoded size of every value found at the same
+// path, with array indexes collapsed into []
+type pathStats struct {
+	count, size int
+}
+
+func collectStats(value interface{}) *docStats {
+	stats := &docStats{
+		keys:  make(map[string]int),
+		paths: make(map[string]*pathStats),
+	}
+	stats.walk(value, "$", "$", 1)
+	return stats
+}
+
+// walk records value and its children, returning the size in bytes of its
+// compact encoding
+func (s *docStats) walk(value interface{}, path, pattern string, level int) (size int) {
+	s.maxDepth = max(s.maxDepth, level)
+	switch v := value.(type) {
+	case nil:
+		s.nulls++
+		size = 4
+	case bool:
+		s.booleans++
+		size = len(strconv.FormatBool(v))
+	case float64:
+		s.numbers++
+		size = len(formatFloat(v))
+	case Number:
+		s.numbers++
+		size = len(v)
+	case string:
+		s.strings++
+		if length := utf8.RuneCountInString(v); length > s.longestString || s.longestStringPath == "" {
+			s.longestString = length
+			s.longestStringPath = path
+		}
+		size = len(quote(v))
+	case []interface{}:
+		s.arrays++
+		if len(v) > s.largestArray || s.largestArrayPath == "" {
+			s.largestArray = len(v)
+			s.largestArrayPath = path
+		}
+		size = 2 + max(len(v)-1, 0)
+		for i, item := range v {
+			size += s.walk(item, fmt.Sprintf("%s[%d]", path, i), pattern+"[]", level+1)
+		}
+	case map[string]interface{}:
+		s.objects++
+		size = 2 + max(len(v)-1, 0)
+		for _, key := range sortedKeys(v) {
+			s.keys[key]++
+			size += len(quote(key)) + 1
+			size += s.walk(v[key], path+pathKey(key), pattern+pathKey(key), level+1)
+		}
+	}
+	p, ok := s.paths[pattern]
+	if !ok {
+		p = &pathStats{}
+		s.paths[pattern] = p
+	}
+	p.count++
+	p.size += size
+	return
+}
+
+func pathKey(key string) string {
+	if key == "" {
+		return `[""]`
+	}
+	for i := 0; i < len(key); i++ {
+		if !isIdentifierChar(key[i], i > 0) {
+			return "[" + quote(key) + "]"
+		}
+	}
+	return "." + key
+}
+
+func (s *docStats) print(w io.Writer, top int) {
+	total := s.objects + s.arrays + s.strings + s.numbers + s.booleans + s.nulls
+	fmt.Fprintf(w, "values: %d\n", total)
+	fmt.Fprintf(w, "  objects:  %d\n", s.objects)
+	fmt.Fprintf(w, "  arrays:   %d\n", s.arrays)
+	fmt.Fprintf(w, "  strings:  %d\n", s.strings)
+	fmt.Fprintf(w, "  numbers:  %d\n", s.numbers)
+	fmt.Fprintf(w, "  booleans: %d\n", s.booleans)
+	fmt.Fprintf(w, "  nulls:    %d\n", s.nulls)
+	fmt.Fprintf(w, "max depth: %d\n", s.maxDepth)
+	if s.longestStringPath != "" {
+		fmt.Fprintf(w, "longest string: %d chars at %s\n", s.longestString, s.longestStringPath)
+	}
+	if s.largestArrayPath != "" {
+		fmt.Fprintf(w, "largest array: %d elements at %s\n", s.largestArray, s.largestArrayPath)
+	}
+
+	if len(s.keys) > 0 {
+		keys := make([]string, 0, len(s.keys))
+		for key := range s.keys {
+			keys = append(keys, key)
+		}
+		slices.SortFunc(keys, func(a, b string) int {
+			if s.keys[a] != s.keys[b] {
+				return s.keys[b] - s.keys[a]
+			}
+			if a < b {
+				return -1
+			}
+			return 1
+		})
+		fmt.Fprintf(w, "most frequent keys:\n")
+		for _, key := range keys[:min(top, len(keys))] {
+			fmt.Fprintf(w, "%9d  %s\n", s.keys[key], quote(key))
+		}
+	}
+
+	paths := make([]string, 0, len(s.paths))
+	for path := range s.paths {
+		paths = append(paths, path)
+	}
+	slices.SortFunc(paths, func(a, b string) int {
+		if s.paths[a].size != s.paths[b].size {
+			return s.paths[b].size - s.paths[a].size
+		}
+		if a < b {
+			return -1
+		}
+		return 1
+	})
+	fmt.Fprintf(w, "size by path:\n")
+	fmt.Fprintf(w, "%9s %9s  %s\n", "bytes", "count", "path")
+	for _, path := range paths[:min(top, len(paths))] {
+		fmt.Fprintf(w, "%9d %9d  %s\n", s.paths[path].size, s.paths[path].count, path)
+	}
+}
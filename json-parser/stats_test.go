@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCollectStats(t *testing.T) {
+	testCases := []struct {
+		input                                              string
+		objects, arrays, strings, numbers, booleans, nulls int
+		maxDepth, longestString                            int
+		longestStringPath                                  string
+		largestArray                                       int
+		largestArrayPath                                   string
+	}{
+		{`1`, 0, 0, 0, 1, 0, 0, 1, 0, "", 0, ""},
+		{`[]`, 0, 1, 0, 0, 0, 0, 1, 0, "", 0, "$"},
+		{`{"a": [1, "xyz", {"a": null, "b": true}], "c d": "é", "e": []}`, 2, 2, 2, 1, 1, 1, 4, 3, "$.a[1]", 3, "$.a"},
+		{`[[[["日本語"]]], ["ab", "cd"]]`, 0, 5, 3, 0, 0, 0, 5, 3, `$[0][0][0][0]`, 2, "$"},
+		{`{"": {"x-y": [false, null, 0]}}`, 2, 1, 0, 1, 1, 1, 4, 0, "", 3, `$[""]["x-y"]`},
+	}
+	for _, c := range testCases {
+		value, err := parse(c.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := collectStats(value)
+		got := []interface{}{s.objects, s.arrays, s.strings, s.numbers, s.booleans, s.nulls,
+			s.maxDepth, s.longestString, s.longestStringPath, s.largestArray, s.largestArrayPath}
+		want := []interface{}{c.objects, c.arrays, c.strings, c.numbers, c.booleans, c.nulls,
+			c.maxDepth, c.longestString, c.longestStringPath, c.largestArray, c.largestArrayPath}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s: want: %v - got: %v", c.input, want, got)
+				break
+			}
+		}
+	}
+}
+
+func TestPrintStats(t *testing.T) {
+	value, err := parse(`{"a": [1, "xyz", {"a": null, "b": true}], "c d": "é", "e": []}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := map[int]string{
+		3: "most frequent keys:\n" +
+			"        2  \"a\"\n" +
+			"        1  \"b\"\n" +
+			"        1  \"c d\"\n" +
+			"size by path:\n" +
+			"    bytes     count  path\n" +
+			"       53         1  $\n" +
+			"       29         1  $.a\n" +
+			"       25         3  $.a[]\n",
+		0: "most frequent keys:\n" +
+			"size by path:\n" +
+			"    bytes     count  path\n",
+		100: "most frequent keys:\n" +
+			"        2  \"a\"\n" +
+			"        1  \"b\"\n" +
+			"        1  \"c d\"\n" +
+			"        1  \"e\"\n" +
+			"size by path:\n" +
+			"    bytes     count  path\n" +
+			"       53         1  $\n" +
+			"       29         1  $.a\n" +
+			"       25         3  $.a[]\n" +
+			"        4         1  $.a[].a\n" +
+			"        4         1  $.a[].b\n" +
+			"        4         1  $[\"c d\"]\n" +
+			"        2         1  $.e\n",
+	}
+	for top, want := range testCases {
+		var sb strings.Builder
+		collectStats(value).print(&sb, top)
+		got := sb.String()
+		got = got[strings.Index(got, "most frequent keys:"):]
+		if got != want {
+			t.Errorf("top %d: want:\n%s\ngot:\n%s", top, want, got)
+		}
+	}
+}
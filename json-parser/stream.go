@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bufio"
+	"io"
+)
+
+// arrayIterator parses the elements of an array one at a time from a reader,
+// so only the current element is held in memory. Everything before the array
+// is skipped without being parsed, and nothing after it is read.
+type arrayIterator struct {
+	reader  *bufio.Reader
+	started bool
+	done    bool
+	buffer  []byte
+	value   interface{}
+	err     error
+}
+
+// newArrayIterator positions the reader at the start of the array referenced
+// by pointer, which for a stream is the first member with a matching key
+func newArrayIterator(r io.Reader, pointer string) (*arrayIterator, error) {
+	it := &arrayIterator{reader: bufio.NewReaderSize(r, 1024*1024)}
+	parts, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	for _, part := range parts {
+		if err := it.seek(part); err == io.EOF {
+			return nil, ErrNotFound
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	c, err := it.skipSpace()
+	if err == io.EOF {
+		return nil, ErrEmpty
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c != '[' {
+		return nil, ErrArray
+	}
+	return it, nil
+}
+
+// Next parses the following element, returning false at the end of the array
+// or on errors, which are then reported by Err
+func (it *arrayIterator) Next() bool {
+	if it.done {
+		return false
+	}
+	c, err := it.skipSpace()
+	if err == nil && c == ']' && !it.started {
+		it.done = true
+		return false
+	}
+	if err == nil && it.started {
+		switch c {
+		case ']':
+			it.done = true
+			return false
+		case ',':
+			c, err = it.skipSpace()
+		default:
+			err = ErrArray
+		}
+	}
+	it.started = true
+	if err == nil {
+		it.buffer = it.buffer[:0]
+		err = it.readValue(c, true)
+	}
+	if err == nil {
+		it.value, err = parseValue(string(it.buffer))
+	}
+	if err != nil {
+		if err == io.EOF {
+			err = ErrArray
+		}
+		it.err, it.done = err, true
+		return false
+	}
+	return true
+}
+
+func (it *arrayIterator) Value() interface{} {
+	return it.value
+}
+
+func (it *arrayIterator) Err() error {
+	return it.err
+}
+
+// seek moves past the key or index part of the current object or array,
+// leaving the reader at the start of its value
+func (it *arrayIterator) seek(part string) error {
+	open, err := it.skipSpace()
+	if err != nil {
+		return ErrNotFound
+	}
+	index, isIndex := arrayIndex(part)
+	if open != '{' && (open != '[' || !isIndex) {
+		return ErrNotFound
+	}
+	for i := 0; ; i++ {
+		c, err := it.skipSpace()
+		if err != nil {
+			return err
+		}
+		if c == '}' || c == ']' {
+			return ErrNotFound
+		}
+		if i > 0 {
+			if c != ',' {
+				return ErrToken
+			}
+			if c, err = it.skipSpace(); err != nil {
+				return err
+			}
+		}
+		if open == '[' {
+			if i == index {
+				return it.reader.UnreadByte()
+			}
+		} else {
+			if c != '"' {
+				return ErrObject
+			}
+			it.buffer = it.buffer[:0]
+			if err := it.readValue(c, true); err != nil {
+				return err
+			}
+			key, err := parseValue(string(it.buffer))
+			if err != nil {
+				return err
+			}
+			if c, err = it.skipSpace(); err != nil || c != ':' {
+				return ErrObject
+			}
+			if key == part {
+				return nil
+			}
+			if c, err = it.skipSpace(); err != nil {
+				return err
+			}
+		}
+		if err := it.readValue(c, false); err != nil {
+			return err
+		}
+	}
+}
+
+func (it *arrayIterator) skipSpace() (byte, error) {
+	for {
+		c, err := it.reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch c {
+		case ' ', '\t', '\n', '\r':
+		default:
+			return c, nil
+		}
+	}
+}
+
+// readValue consumes the bytes of the value starting with c, keeping them in
+// the buffer when asked to. Values are only checked for balanced brackets
+// here, as the ones kept are parsed afterwards.
+func (it *arrayIterator) readValue(c byte, keep bool) (err error) {
+	if c != '"' && c != '[' && c != '{' {
+		// number or keyword, up to the next delimiter
+		for {
+			if keep {
+				it.buffer = append(it.buffer, c)
+			}
+			c, err = it.reader.ReadByte()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			switch c {
+			case ',', ':', '[', ']', '{', '}', '"', ' ', '\t', '\n', '\r':
+				return it.reader.UnreadByte()
+			}
+		}
+	}
+
+	nesting := 0
+	inString, escaping := false, false
+	for {
+		switch {
+		case inString:
+			if escaping {
+				escaping = false
+			} else if c == '\\' {
+				escaping = true
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '[' || c == '{':
+			nesting++
+		case c == ']' || c == '}':
+			nesting--
+		}
+		if keep {
+			it.buffer = append(it.buffer, c)
+		}
+		if nesting == 0 && !inString {
+			return nil
+		}
+		c, err = it.reader.ReadByte()
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestArrayIterator(t *testing.T) {
+	testCases := []struct {
+		input, pointer string
+		want           []string
+		err            error
+	}{
+		{`[1, "a", {"b": [2]}, null]`, "", []string{`1`, `"a"`, `{"b":[2]}`, `null`}, nil},
+		{` [ ] `, "", nil, nil},
+		{`{"x": 1, "items": [], "y": 2}`, "/items", nil, nil},
+		{`{"skip": {"items": [0]}, "items": [1, 2]}`, "/items", []string{`1`, `2`}, nil},
+		{`{"a": [[0], {"b": ["x", "]"]}]}`, "/a/1/b", []string{`"x"`, `"]"`}, nil},
+		{`[[1], [2, 3]]`, "/1", []string{`2`, `3`}, nil},
+		{`[1, 2,]`, "", []string{`1`, `2`}, ErrToken},
+		{`[1 2]`, "", []string{`1`}, ErrArray},
+		{`[1, {"a": `, "", []string{`1`}, ErrArray},
+		{`[1, 2`, "", []string{`1`, `2`}, ErrArray},
+	}
+	for _, c := range testCases {
+		it, err := newArrayIterator(strings.NewReader(c.input), c.pointer)
+		if err != nil {
+			t.Errorf("%s %s: %v", c.input, c.pointer, err)
+			continue
+		}
+		var got []string
+		for it.Next() {
+			got = append(got, marshal(it.Value()))
+		}
+		if strings.Join(got, " ") != strings.Join(c.want, " ") {
+			t.Errorf("%s %s: want: %v - got: %v", c.input, c.pointer, c.want, got)
+		}
+		if err := it.Err(); !errors.Is(err, c.err) {
+			t.Errorf("%s %s: want: %v - got: %v", c.input, c.pointer, c.err, err)
+		}
+		if it.Next() {
+			t.Errorf("%s %s: Next went on after the end", c.input, c.pointer)
+		}
+	}
+
+	for _, c := range []struct {
+		input, pointer string
+		err            error
+	}{
+		{`{"a": 1}`, "", ErrArray},
+		{`{"a": 1}`, "/a", ErrArray},
+		{`"text"`, "", ErrArray},
+		{``, "", ErrEmpty},
+		{`{"a": [1]}`, "/b", ErrNotFound},
+		{`[[1]]`, "/1", ErrNotFound},
+		{`[[1]]`, "/x", ErrNotFound},
+		{`{"a": [1]}`, "/a/0/b", ErrNotFound},
+		{`{"a": [1]`, "/b", ErrNotFound},
+	} {
+		_, err := newArrayIterator(strings.NewReader(c.input), c.pointer)
+		if !errors.Is(err, c.err) {
+			t.Errorf("%s %s: want: %v - got: %v", c.input, c.pointer, c.err, err)
+		}
+	}
+}
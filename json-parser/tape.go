@@ -0,0 +1,449 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// The tape backend parses in two passes, in the style of simdjson. The first
+// one indexes the offsets of the structural characters and the start of every
+// scalar, and the second one walks the index writing a flat tape of 64 bit
+// entries. Containers record the position of the entry closing them, so
+// values can be skipped without looking at their contents.
+//
+// Each entry keeps its type in the high byte and a payload in the others:
+//
+//	'{' '['  element count << 32 | position of the closing entry, the count
+//	         saturating at tapeMaxCount
+//	'}' ']'  position of the opening entry
+//	'"'      index of the decoded string
+//	'0'      index of the literal, followed by an entry with the float64 bits
+//	't' 'f' 'n'
+type tape struct {
+	entries []uint64
+	strings []string
+}
+
+const tapePayloadMask = 1<<56 - 1
+const tapeMaxCount = 1<<24 - 1
+
+const (
+	stateValue byte = iota
+	stateElementOrClose
+	stateKey
+	stateKeyOrClose
+	stateColon
+	stateAfterValue
+)
+
+// structuralIndex returns the offsets of brackets, colons and commas outside
+// of strings, along with the offset where each string, number or keyword
+// starts
+func structuralIndex(data string) ([]int, error) {
+	index := make([]int, 0, len(data)/4)
+	inScalar := false
+	for i := 0; i < len(data); i++ {
+		switch c := data[i]; c {
+		case '{', '}', '[', ']', ':', ',':
+			index = append(index, i)
+			inScalar = false
+		case ' ', '\t', '\n', '\r':
+			inScalar = false
+		case '"':
+			index = append(index, i)
+			for i++; i < len(data) && data[i] != '"'; i++ {
+				if data[i] < 0x20 {
+					return nil, ErrString
+				}
+				if data[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(data) {
+				return nil, ErrString
+			}
+			inScalar = false
+		default:
+			if !inScalar {
+				index = append(index, i)
+				inScalar = true
+			}
+		}
+	}
+	return index, nil
+}
+
+func buildTape(data string) (*tape, error) {
+	if relaxed {
+		var err error
+		if data, err = blankComments(data); err != nil {
+			return nil, err
+		}
+	}
+	index, err := structuralIndex(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(index) == 0 {
+		return nil, ErrEmpty
+	}
+
+	t := &tape{entries: make([]uint64, 0, len(index))}
+	var open []int
+	state := stateValue
+	for n, start := range index {
+		end := len(data)
+		if n+1 < len(index) {
+			end = index[n+1]
+		}
+		c := data[start]
+		switch state {
+		case stateValue, stateElementOrClose:
+			if c == ']' && state == stateElementOrClose {
+				open, state = t.close(open, ']'), stateAfterValue
+				continue
+			}
+			if len(open)+1 > maxDepth {
+				return nil, ErrMaxDepth
+			}
+			t.count(open)
+			switch c {
+			case '{':
+				open = append(open, len(t.entries))
+				t.append('{', 0)
+				state = stateKeyOrClose
+			case '[':
+				open = append(open, len(t.entries))
+				t.append('[', 0)
+				state = stateElementOrClose
+			default:
+				if err := t.appendScalar(data[start:end]); err != nil {
+					return nil, err
+				}
+				state = stateAfterValue
+			}
+		case stateKey, stateKeyOrClose:
+			if c == '}' && state == stateKeyOrClose {
+				open, state = t.close(open, '}'), stateAfterValue
+				continue
+			}
+			if c != '"' {
+				return nil, ErrObject
+			}
+			if err := t.appendScalar(data[start:end]); err != nil {
+				return nil, err
+			}
+			state = stateColon
+		case stateColon:
+			if c != ':' {
+				return nil, ErrObject
+			}
+			state = stateValue
+		case stateAfterValue:
+			if len(open) == 0 {
+				return nil, ErrToken
+			}
+			container := byte(t.entries[open[len(open)-1]] >> 56)
+			switch {
+			case c == ',' && container == '[':
+				state = stateValue
+			case c == ',':
+				state = stateKey
+			case c == ']' && container == '[', c == '}' && container == '{':
+				open = t.close(open, c)
+			case container == '[':
+				return nil, ErrArray
+			default:
+				return nil, ErrObject
+			}
+		}
+	}
+
+	switch {
+	case len(open) > 0 && byte(t.entries[open[len(open)-1]]>>56) == '[':
+		return nil, ErrArray
+	case len(open) > 0:
+		return nil, ErrObject
+	case state != stateAfterValue:
+		return nil, ErrToken
+	}
+	return t, nil
+}
+
+// blankComments replaces the comments allowed by -relaxed with spaces, so the
+// offsets of everything else stay the same
+func blankComments(data string) (string, error) {
+	if !strings.Contains(data, "/") {
+		return data, nil
+	}
+	b := []byte(data)
+	for i := 0; i < len(b); i++ {
+		switch {
+		case b[i] == '"':
+			for i++; i < len(b) && b[i] != '"'; i++ {
+				if b[i] == '\\' {
+					i++
+				}
+			}
+		case strings.HasPrefix(data[i:], "//"):
+			for ; i < len(b) && b[i] != '\n'; i++ {
+				b[i] = ' '
+			}
+		case strings.HasPrefix(data[i:], "/*"):
+			end := strings.Index(data[i+2:], "*/")
+			if end < 0 {
+				return "", ErrToken
+			}
+			for end += i + 4; i < end; i++ {
+				b[i] = ' '
+			}
+			i--
+		}
+	}
+	return string(b), nil
+}
+
+func (t *tape) append(kind byte, payload uint64) {
+	t.entries = append(t.entries, uint64(kind)<<56|payload&tapePayloadMask)
+}
+
+// count adds a value to the container being filled, if there is one
+func (t *tape) count(open []int) {
+	if len(open) > 0 && t.entries[open[len(open)-1]]>>32&tapeMaxCount < tapeMaxCount {
+		t.entries[open[len(open)-1]] += 1 << 32
+	}
+}
+
+func (t *tape) close(open []int, kind byte) []int {
+	start := open[len(open)-1]
+	t.entries[start] |= uint64(len(t.entries))
+	t.append(kind, uint64(start))
+	return open[:len(open)-1]
+}
+
+// appendScalar writes the string, number or keyword at the start of text,
+// which may be followed only by whitespace
+func (t *tape) appendScalar(text string) error {
+	text = strings.TrimRight(text, " \t\n\r")
+	switch c := text[0]; {
+	case c == '"':
+		s, err := decodeString(text)
+		if err != nil {
+			return err
+		}
+		t.append('"', uint64(len(t.strings)))
+		t.strings = append(t.strings, s)
+	case c == '-' || c >= '0' && c <= '9':
+		if !validNumber(text) {
+			return ErrNumber
+		}
+		value, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return ErrNumber
+		}
+		t.append('0', uint64(len(t.strings)))
+		t.strings = append(t.strings, text)
+		t.entries = append(t.entries, math.Float64bits(value))
+	case text == "true":
+		t.append('t', 0)
+	case text == "false":
+		t.append('f', 0)
+	case text == "null":
+		t.append('n', 0)
+	default:
+		return ErrKeyWord
+	}
+	return nil
+}
+
+// decodeString returns the content of a string literal, using the tokenizer
+// only for the ones with escape sequences
+func decodeString(literal string) (string, error) {
+	if len(literal) < 2 || literal[len(literal)-1] != '"' {
+		return "", ErrString
+	}
+	if strings.IndexByte(literal, '\\') < 0 {
+		return literal[1 : len(literal)-1], nil
+	}
+	tokens, err := tokenize(literal)
+	if err != nil {
+		return "", err
+	}
+	if len(tokens) != 1 || tokens[0].Type != 'S' {
+		return "", ErrString
+	}
+	return tokens[0].Content, nil
+}
+
+// validNumber checks the number grammar of RFC 8259
+func validNumber(text string) bool {
+	i := 0
+	if i < len(text) && text[i] == '-' {
+		i++
+	}
+	digits := func() int {
+		start := i
+		for i < len(text) && text[i] >= '0' && text[i] <= '9' {
+			i++
+		}
+		return i - start
+	}
+	if i < len(text) && text[i] == '0' {
+		i++
+	} else if digits() == 0 {
+		return false
+	}
+	if i < len(text) && text[i] == '.' {
+		i++
+		if digits() == 0 {
+			return false
+		}
+	}
+	if i < len(text) && (text[i] == 'e' || text[i] == 'E') {
+		i++
+		if i < len(text) && (text[i] == '+' || text[i] == '-') {
+			i++
+		}
+		if digits() == 0 {
+			return false
+		}
+	}
+	return i == len(text)
+}
+
+// tapeValue points at the entry of a value in the tape
+type tapeValue struct {
+	tape  *tape
+	index int
+}
+
+func (t *tape) root() tapeValue {
+	return tapeValue{t, 0}
+}
+
+func (v tapeValue) kind() byte {
+	return byte(v.tape.entries[v.index] >> 56)
+}
+
+func (v tapeValue) payload() uint64 {
+	return v.tape.entries[v.index] & tapePayloadMask
+}
+
+// next returns the position of the entry following the value
+func (v tapeValue) next() int {
+	switch v.kind() {
+	case '{', '[':
+		return int(v.payload()&math.MaxUint32) + 1
+	case '0':
+		return v.index + 2
+	}
+	return v.index + 1
+}
+
+// length returns the number of elements or members of a container
+func (v tapeValue) length() int {
+	n := int(v.payload() >> 32)
+	if n < tapeMaxCount {
+		return n
+	}
+	n = 0
+	for i := v.index + 1; i < int(v.payload()&math.MaxUint32); i = (tapeValue{v.tape, i}).next() {
+		if v.kind() == '{' {
+			i++
+		}
+		n++
+	}
+	return n
+}
+
+// member returns the value of the last member with the given key
+func (v tapeValue) member(key string) (result tapeValue, found bool) {
+	if v.kind() != '{' {
+		return
+	}
+	for i := v.index + 1; byte(v.tape.entries[i]>>56) != '}'; {
+		value := tapeValue{v.tape, i + 1}
+		if v.tape.strings[v.tape.entries[i]&tapePayloadMask] == key {
+			result, found = value, true
+		}
+		i = value.next()
+	}
+	return
+}
+
+func (v tapeValue) element(n int) (tapeValue, bool) {
+	if v.kind() != '[' || n < 0 || n >= v.length() {
+		return tapeValue{}, false
+	}
+	i := v.index + 1
+	for ; n > 0; n-- {
+		i = tapeValue{v.tape, i}.next()
+	}
+	return tapeValue{v.tape, i}, true
+}
+
+func (v tapeValue) lookup(pointer string) (tapeValue, error) {
+	parts, err := parsePointer(pointer)
+	if err != nil {
+		return v, err
+	}
+	for _, part := range parts {
+		var found bool
+		if v.kind() == '[' {
+			index, ok := arrayIndex(part)
+			v, found = v.element(index)
+			found = found && ok
+		} else {
+			v, found = v.member(part)
+		}
+		if !found {
+			return v, ErrNotFound
+		}
+	}
+	return v, nil
+}
+
+// lookupValue converts the value at pointer, or gives ErrNotFound as
+// lookupPointer does
+func (t *tape) lookupValue(pointer string) (interface{}, error) {
+	v, err := t.root().lookup(pointer)
+	if err != nil {
+		return nil, err
+	}
+	return v.value(), nil
+}
+
+// value converts the tape from v on into the value model used by parse
+func (v tapeValue) value() interface{} {
+	switch v.kind() {
+	case '{':
+		obj := make(map[string]interface{}, v.length())
+		for i := v.index + 1; byte(v.tape.entries[i]>>56) != '}'; {
+			value := tapeValue{v.tape, i + 1}
+			obj[v.tape.strings[v.tape.entries[i]&tapePayloadMask]] = value.value()
+			i = value.next()
+		}
+		return obj
+	case '[':
+		arr := make([]interface{}, 0, v.length())
+		for i := v.index + 1; byte(v.tape.entries[i]>>56) != ']'; {
+			element := tapeValue{v.tape, i}
+			arr = append(arr, element.value())
+			i = element.next()
+		}
+		return arr
+	case '"':
+		return v.tape.strings[v.payload()]
+	case '0':
+		if useNumber {
+			return Number(v.tape.strings[v.payload()])
+		}
+		return math.Float64frombits(v.tape.entries[v.index+1])
+	case 't':
+		return true
+	case 'f':
+		return false
+	}
+	return nil
+}
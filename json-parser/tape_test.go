@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTapeMatchesRecursiveDescent(t *testing.T) {
+	files, _ := filepath.Glob("json_checker/pass*.json")
+	steps, _ := filepath.Glob("tests/*/valid*.json")
+	for _, name := range append(files, steps...) {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := parse(string(data))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		tape, err := buildTape(string(data))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if got := tape.root().value(); marshal(got) != marshal(want) {
+			t.Errorf("%s: want: %s - got: %s", name, marshal(want), marshal(got))
+		}
+	}
+}
+
+func TestTapeLookup(t *testing.T) {
+	tape, err := buildTape(`{"a": [1, {"b": "x"}, [2, 3]], "c": null, "a/b": true, "d": 1, "d": 2}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := map[string]string{
+		"":       `{"a":[1,{"b":"x"},[2,3]],"a/b":true,"c":null,"d":2}`,
+		"/a/1/b": `"x"`,
+		"/a/2/1": `3`,
+		"/c":     `null`,
+		"/a~1b":  `true`,
+		"/d":     `2`,
+	}
+	for pointer, want := range testCases {
+		v, err := tape.root().lookup(pointer)
+		if err != nil {
+			t.Errorf("%q: %v", pointer, err)
+		} else if got := marshal(v.value()); got != want {
+			t.Errorf("%q: want: %s - got: %s", pointer, want, got)
+		}
+	}
+	for _, pointer := range []string{"/x", "/a/3", "/a/01", "/c/0", "a"} {
+		if _, err := tape.root().lookup(pointer); err == nil {
+			t.Errorf("%q: expected an error", pointer)
+		}
+	}
+	for _, pointer := range []string{"/x", "/a/5", "/a/1/c"} {
+		if _, err := tape.lookupValue(pointer); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%q: want: %v - got: %v", pointer, ErrNotFound, err)
+		}
+	}
+}
+
+func TestTapeRelaxed(t *testing.T) {
+	saved := relaxed
+	relaxed = true
+	defer func() {
+		relaxed = saved
+	}()
+	for _, input := range []string{
+		"[1, /* c */ \"é\" // x\n, true]",
+		"{\"a\"/**/:1}// end",
+		"{\"a/*b*/\": \"//\", \"c\": [null/* */]}",
+		"/* leading */ 1",
+	} {
+		want, err := parse(input)
+		if err != nil {
+			t.Fatalf("%q: %v", input, err)
+		}
+		tape, err := buildTape(input)
+		if err != nil {
+			t.Errorf("%q: %v", input, err)
+		} else if got := tape.root().value(); marshal(got) != marshal(want) {
+			t.Errorf("%q: want: %s - got: %s", input, marshal(want), marshal(got))
+		}
+	}
+	for _, input := range []string{"[1 /* open", "[1, / 2]", "[1/2]"} {
+		if _, err := buildTape(input); err == nil {
+			t.Errorf("%q: expected an error", input)
+		}
+	}
+}
+
+func benchmarkDocument() string {
+	var sb strings.Builder
+	sb.WriteString(`{"items": [`)
+	for i := 0; i < 10000; i++ {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		fmt.Fprintf(&sb, `{"id": %d, "name": "item %d", "price": %d.5, "tags": ["a", "b\n"], "active": true}`, i, i, i)
+	}
+	sb.WriteString(`], "total": 10000}`)
+	return sb.String()
+}
+
+func BenchmarkParseTokens(b *testing.B) {
+	data := benchmarkDocument()
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		if _, err := parse(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseTape(b *testing.B) {
+	data := benchmarkDocument()
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		tape, err := buildTape(data)
+		if err != nil {
+			b.Fatal(err)
+		}
+		tape.root().value()
+	}
+}
+
+func BenchmarkLookupTokens(b *testing.B) {
+	data := benchmarkDocument()
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		result, err := parse(data)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := lookupPointer(result, "/items/9999/name"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLookupTape(b *testing.B) {
+	data := benchmarkDocument()
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		tape, err := buildTape(data)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := tape.root().lookup("/items/9999/name"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
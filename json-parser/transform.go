@@ -0,0 +1,266 @@
+package main
+
+import (
+	"cmp"
+	"path"
+	"slices"
+	"strconv"
+)
+
+type transform func(interface{}) interface{}
+
+// transforms are applied in the order of the flags adding them, to the value
+// before it is written
+var transforms []transform
+
+// transformFlag adds a transform each time it is set, so that transforms can be
+// repeated and combined in any order
+type transformFlag struct {
+	build   func(arg string) (transform, error)
+	boolean bool
+}
+
+func (f transformFlag) String() string {
+	return ""
+}
+
+func (f transformFlag) Set(arg string) error {
+	if f.boolean && arg == "false" {
+		return nil
+	}
+	t, err := f.build(arg)
+	if err != nil {
+		return err
+	}
+	transforms = append(transforms, t)
+	return nil
+}
+
+func (f transformFlag) IsBoolFlag() bool {
+	return f.boolean
+}
+
+func applyTransforms(value interface{}) interface{} {
+	for _, t := range transforms {
+		value = t(value)
+	}
+	return value
+}
+
+// walkValue rebuilds a value bottom up, passing each object and array to f
+// after their contents
+func walkValue(value interface{}, f func(interface{}) interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			obj[key] = walkValue(item, f)
+		}
+		return f(obj)
+	case []interface{}:
+		arr := make([]interface{}, len(v))
+		for i, item := range v {
+			arr[i] = walkValue(item, f)
+		}
+		return f(arr)
+	}
+	return f(value)
+}
+
+func dropNulls(string) (transform, error) {
+	return func(value interface{}) interface{} {
+		return walkValue(value, func(v interface{}) interface{} {
+			if obj, ok := v.(map[string]interface{}); ok {
+				for key, item := range obj {
+					if item == nil {
+						delete(obj, key)
+					}
+				}
+			}
+			return v
+		})
+	}, nil
+}
+
+// dropKeys removes the members whose key matches a pattern of path.Match, at
+// any depth
+func dropKeys(pattern string) (transform, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	return func(value interface{}) interface{} {
+		return walkValue(value, func(v interface{}) interface{} {
+			if obj, ok := v.(map[string]interface{}); ok {
+				for key := range obj {
+					if matched, _ := path.Match(pattern, key); matched {
+						delete(obj, key)
+					}
+				}
+			}
+			return v
+		})
+	}, nil
+}
+
+// redact replaces the values at a JSON Pointer, where a * token stands for
+// every member or element, with a placeholder. Paths not found are ignored.
+func redact(pointer string) (transform, error) {
+	parts, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	return func(value interface{}) interface{} {
+		return redactPath(value, parts)
+	}, nil
+}
+
+func redactPath(value interface{}, parts []string) interface{} {
+	if len(parts) == 0 {
+		return "[REDACTED]"
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			if parts[0] == "*" || parts[0] == key {
+				item = redactPath(item, parts[1:])
+			}
+			obj[key] = item
+		}
+		return obj
+	case []interface{}:
+		arr := make([]interface{}, len(v))
+		for i, item := range v {
+			if parts[0] == "*" || parts[0] == strconv.Itoa(i) {
+				item = redactPath(item, parts[1:])
+			}
+			arr[i] = item
+		}
+		return arr
+	}
+	return value
+}
+
+// sortArrays sorts the arrays made only of scalars
+func sortArrays(string) (transform, error) {
+	return func(value interface{}) interface{} {
+		return walkValue(value, func(v interface{}) interface{} {
+			if arr, ok := v.([]interface{}); ok {
+				for _, item := range arr {
+					switch item.(type) {
+					case map[string]interface{}, []interface{}:
+						return v
+					}
+				}
+				slices.SortStableFunc(arr, compareValues)
+			}
+			return v
+		})
+	}, nil
+}
+
+// sortBy sorts the arrays made only of objects by the value of one of their
+// members, the objects without it going last
+func sortBy(key string) (transform, error) {
+	return func(value interface{}) interface{} {
+		return walkValue(value, func(v interface{}) interface{} {
+			arr, ok := v.([]interface{})
+			if !ok {
+				return v
+			}
+			for _, item := range arr {
+				if _, ok := item.(map[string]interface{}); !ok {
+					return v
+				}
+			}
+			slices.SortStableFunc(arr, func(a, b interface{}) int {
+				x, foundX := a.(map[string]interface{})[key]
+				y, foundY := b.(map[string]interface{})[key]
+				if foundX != foundY {
+					if foundX {
+						return -1
+					}
+					return 1
+				}
+				return compareValues(x, y)
+			})
+			return v
+		})
+	}, nil
+}
+
+// unique removes the elements of arrays equal to an earlier one
+func unique(string) (transform, error) {
+	return func(value interface{}) interface{} {
+		return walkValue(value, func(v interface{}) interface{} {
+			arr, ok := v.([]interface{})
+			if !ok {
+				return v
+			}
+			seen := map[string]bool{}
+			result := arr[:0]
+			for _, item := range arr {
+				if encoded := canonicalValue(item); !seen[encoded] {
+					seen[encoded] = true
+					result = append(result, item)
+				}
+			}
+			return result
+		})
+	}, nil
+}
+
+// canonicalValue encodes a value so that equal values, including numbers
+// written differently, have the same encoding
+func canonicalValue(value interface{}) string {
+	return marshal(walkValue(value, func(item interface{}) interface{} {
+		if n, ok := item.(Number); ok {
+			f, _ := n.Float64()
+			return f
+		}
+		return item
+	}))
+}
+
+// compareValues orders values by type, null, booleans, numbers, strings,
+// arrays and objects, and then by value within the same type
+func compareValues(a, b interface{}) int {
+	rank := func(v interface{}) int {
+		switch v := v.(type) {
+		case nil:
+			return 0
+		case bool:
+			if v {
+				return 2
+			}
+			return 1
+		case float64, Number:
+			return 3
+		case string:
+			return 4
+		case []interface{}:
+			return 5
+		}
+		return 6
+	}
+	if c := cmp.Compare(rank(a), rank(b)); c != 0 {
+		return c
+	}
+	switch x := a.(type) {
+	case float64, Number:
+		return cmp.Compare(numberValue(x), numberValue(b))
+	case string:
+		return cmp.Compare(x, b.(string))
+	case []interface{}, map[string]interface{}:
+		return cmp.Compare(canonicalValue(a), canonicalValue(b))
+	}
+	return 0
+}
+
+func numberValue(value interface{}) float64 {
+	if n, ok := value.(Number); ok {
+		f, _ := n.Float64()
+		return f
+	}
+	return value.(float64)
+}
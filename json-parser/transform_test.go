@@ -0,0 +1,78 @@
+package main
+
+import "testing"
+
+func TestTransforms(t *testing.T) {
+	withNumbers(t)
+	testCases := []struct {
+		name             string
+		build            func(string) (transform, error)
+		arg, input, want string
+	}{
+		{"dropNulls", dropNulls, "", `{"a": null, "b": [null, {"c": null, "d": 1}]}`, `{"b":[null,{"d":1}]}`},
+		{"dropNulls", dropNulls, "", `null`, `null`},
+		{"dropKeys", dropKeys, "*_id", `{"user_id": 1, "id": 2, "x": [{"a_id": 3, "b": 4}]}`, `{"id":2,"x":[{"b":4}]}`},
+		{"dropKeys", dropKeys, "[ab]", `{"a": 1, "b": {"a": 2, "c": 3}, "ab": 4}`, `{"ab":4}`},
+		{"redact", redact, "/a/b", `{"a": {"b": 1, "c": 2}}`, `{"a":{"b":"[REDACTED]","c":2}}`},
+		{"redact", redact, "/*/password", `[{"password": "x"}, {"user": "y"}]`, `[{"password":"[REDACTED]"},{"user":"y"}]`},
+		{"redact", redact, "/list/*", `{"list": [1, [2]], "other": 3}`, `{"list":["[REDACTED]","[REDACTED]"],"other":3}`},
+		{"redact", redact, "/missing/x", `{"a": 1}`, `{"a":1}`},
+		{"redact", redact, "/a~1b", `{"a/b": 1, "a": {"b": 2}}`, `{"a":{"b":2},"a/b":"[REDACTED]"}`},
+		{"sortArrays", sortArrays, "", `[3, "b", null, true, 1.5, "a", false]`, `[null,false,true,1.5,3,"a","b"]`},
+		{"sortArrays", sortArrays, "", `{"a": [2, 10, 1e0], "b": [[2], 1]}`, `{"a":[1e0,2,10],"b":[[2],1]}`},
+		{"sortBy", sortBy, "n", `[{"n": 2}, {"m": 0}, {"n": 1, "x": "a"}, {"n": 1, "x": "b"}]`, `[{"n":1,"x":"a"},{"n":1,"x":"b"},{"n":2},{"m":0}]`},
+		{"sortBy", sortBy, "n", `{"a": [{"n": "b"}, {"n": "a"}], "b": [{"n": 2}, 1]}`, `{"a":[{"n":"a"},{"n":"b"}],"b":[{"n":2},1]}`},
+		{"unique", unique, "", `[1, 1.0, "1", 1, [1], [1.0], {"a": 1}, {"a": 1}]`, `[1,"1",[1],{"a":1}]`},
+		{"unique", unique, "", `{"a": [null, null], "b": []}`, `{"a":[null],"b":[]}`},
+	}
+	for _, c := range testCases {
+		value, err := parse(c.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := c.build(c.arg)
+		if err != nil {
+			t.Errorf("%s %s: %v", c.name, c.arg, err)
+			continue
+		}
+		if got := marshal(f(value)); got != c.want {
+			t.Errorf("%s %s %s: want: %s - got: %s", c.name, c.arg, c.input, c.want, got)
+		}
+	}
+
+	if _, err := dropKeys("[a"); err == nil {
+		t.Errorf("dropKeys [a: expected an error")
+	}
+	if _, err := redact("a"); err == nil {
+		t.Errorf("redact a: expected an error")
+	}
+}
+
+func TestApplyTransforms(t *testing.T) {
+	withNumbers(t)
+	saved := transforms
+	defer func() {
+		transforms = saved
+	}()
+	transforms = nil
+	for _, flag := range []struct {
+		f   transformFlag
+		arg string
+	}{
+		{transformFlag{build: dropKeys}, "x"},
+		{transformFlag{build: dropNulls, boolean: true}, "false"},
+		{transformFlag{build: sortArrays, boolean: true}, "true"},
+		{transformFlag{build: unique, boolean: true}, "true"},
+	} {
+		if err := flag.f.Set(flag.arg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	value, err := parse(`{"x": 1, "a": [3, null, 1, 3]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := marshal(applyTransforms(value)), `{"a":[null,1,3]}`; got != want {
+		t.Errorf("want: %s - got: %s", want, got)
+	}
+}
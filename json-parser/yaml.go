@@ -0,0 +1,991 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var ErrYAML = errors.New("invalid yaml")
+var ErrAlias = errors.New("undefined alias")
+var ErrExpansion = errors.New("aliases repeat too many nodes")
+
+// maxAliasNodes bounds the values aliases may repeat in a stream, as aliases
+// of anchors holding aliases grow exponentially when converted
+const maxAliasNodes = 1 << 20
+
+// marshalYAML encodes a value as a YAML document in block style, with object
+// keys in sorted order. Strings are quoted only when they would be read back
+// as something else, using JSON escapes which are valid in YAML too.
+func marshalYAML(value interface{}) string {
+	var sb strings.Builder
+	writeYAMLNode(&sb, value, "")
+	return sb.String()
+}
+
+// writeYAMLNode writes a value from the current position of the line, indent
+// being the indentation of its first line
+func writeYAMLNode(sb *strings.Builder, value interface{}, indent string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			sb.WriteString("{}\n")
+			return
+		}
+		for i, key := range sortedKeys(v) {
+			if i > 0 {
+				sb.WriteString(indent)
+			}
+			sb.WriteString(yamlString(key))
+			sb.WriteByte(':')
+			switch child := v[key].(type) {
+			case map[string]interface{}, []interface{}:
+				if containerLength(child) > 0 {
+					sb.WriteString("\n" + indent + "  ")
+					writeYAMLNode(sb, child, indent+"  ")
+					continue
+				}
+			}
+			sb.WriteByte(' ')
+			writeYAMLScalar(sb, v[key], indent)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			sb.WriteString("[]\n")
+			return
+		}
+		for i, item := range v {
+			if i > 0 {
+				sb.WriteString(indent)
+			}
+			sb.WriteString("- ")
+			writeYAMLNode(sb, item, indent+"  ")
+		}
+	default:
+		writeYAMLScalar(sb, value, indent)
+	}
+}
+
+func containerLength(value interface{}) int {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return len(v)
+	case []interface{}:
+		return len(v)
+	}
+	return 0
+}
+
+// writeYAMLScalar writes a scalar or an empty container, using a literal block
+// for multiline strings
+func writeYAMLScalar(sb *strings.Builder, value interface{}, indent string) {
+	switch v := value.(type) {
+	case string:
+		if literalBlock(v) {
+			text := strings.TrimRight(v, "\n")
+			trailing := len(v) - len(text)
+			switch trailing {
+			case 0:
+				sb.WriteString("|-\n")
+			case 1:
+				sb.WriteString("|\n")
+			default:
+				sb.WriteString("|+\n")
+			}
+			for _, line := range strings.Split(text, "\n") {
+				if line != "" {
+					sb.WriteString(indent + "  " + line)
+				}
+				sb.WriteByte('\n')
+			}
+			sb.WriteString(strings.Repeat("\n", max(trailing-1, 0)))
+			return
+		}
+		sb.WriteString(yamlString(v))
+	case map[string]interface{}:
+		sb.WriteString("{}")
+	case []interface{}:
+		sb.WriteString("[]")
+	default:
+		sb.WriteString(marshal(v))
+	}
+	sb.WriteByte('\n')
+}
+
+// literalBlock tells if a string can be written as a literal block, which
+// keeps it readable but can't represent every string
+func literalBlock(s string) bool {
+	if !strings.Contains(strings.TrimRight(s, "\n"), "\n") || s[0] == ' ' || s[0] == '\n' {
+		return false
+	}
+	for _, c := range s {
+		if c < ' ' && c != '\n' || c == 0x7f || c == 0xfeff {
+			return false
+		}
+	}
+	for _, line := range strings.Split(s, "\n") {
+		if strings.HasSuffix(line, " ") {
+			return false
+		}
+	}
+	return true
+}
+
+// yamlString returns s as a plain scalar when it is read back as the same
+// string, and as a double quoted one otherwise. Booleans of YAML 1.1 are quoted
+// too, as many tools still follow it.
+func yamlString(s string) string {
+	if s == "" || strings.TrimSpace(s) != s || strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`") ||
+		strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return quote(s)
+	}
+	for _, c := range s {
+		if c < ' ' || c == 0x7f || c == 0xfeff {
+			return quote(s)
+		}
+	}
+	switch strings.ToLower(s) {
+	case "y", "n", "yes", "no", "on", "off":
+		return quote(s)
+	}
+	if resolved, ok := resolvePlain(s).(string); !ok || resolved != s {
+		return quote(s)
+	}
+	return s
+}
+
+// resolvePlain converts a plain scalar following the core schema of YAML 1.2.
+// Infinity and NaN, which JSON can't represent, are kept as strings.
+func resolvePlain(text string) interface{} {
+	switch text {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+	if validNumber(text) {
+		return yamlNumber(text)
+	}
+	if n, err := strconv.ParseUint(strings.TrimPrefix(text, "0x"), 16, 64); strings.HasPrefix(text, "0x") && err == nil {
+		return yamlNumber(strconv.FormatUint(n, 10))
+	}
+	if n, err := strconv.ParseUint(strings.TrimPrefix(text, "0o"), 8, 64); strings.HasPrefix(text, "0o") && err == nil {
+		return yamlNumber(strconv.FormatUint(n, 10))
+	}
+	digits := strings.TrimLeft(text, "+-")
+	if len(text)-len(digits) > 1 || digits == "" || strings.ContainsAny(digits, "_xob") {
+		return text
+	}
+	if digits[0] == '.' && len(digits) > 1 && digits[1] >= '0' && digits[1] <= '9' || digits[0] >= '0' && digits[0] <= '9' {
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			return yamlNumber(formatFloat(f))
+		}
+	}
+	return text
+}
+
+func yamlNumber(literal string) interface{} {
+	if useNumber {
+		return Number(literal)
+	}
+	f, _ := strconv.ParseFloat(literal, 64)
+	return f
+}
+
+// yamlParser reads the block and flow styles of YAML, without tags other than
+// !!str, complex keys or multiline plain keys
+type yamlParser struct {
+	lines      []string
+	pos        int
+	anchors    map[string]interface{}
+	aliasNodes int
+}
+
+// parseYAML returns the value of each document in the stream
+func parseYAML(r io.Reader) ([]interface{}, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.ReplaceAll(strings.TrimPrefix(string(data), "\ufeff"), "\r\n", "\n")
+	p := &yamlParser{lines: strings.Split(strings.TrimSuffix(text, "\n"), "\n"), anchors: map[string]interface{}{}}
+
+	var documents []interface{}
+	for {
+		explicit := false
+		for ; p.pos < len(p.lines); p.pos++ {
+			line := p.lines[p.pos]
+			if strings.HasPrefix(line, "%") {
+				continue
+			}
+			if documentMarker(line, "---") {
+				explicit = true
+				p.lines[p.pos] = "   " + line[3:]
+				break
+			}
+			if !blankLine(line) {
+				break
+			}
+		}
+		if _, _, ok := p.peek(); !ok && !explicit {
+			break
+		}
+		value, err := p.parseNode(-1, false)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, value)
+		if _, _, ok := p.peek(); ok {
+			return nil, p.error()
+		}
+		if p.pos < len(p.lines) && documentMarker(p.lines[p.pos], "...") {
+			p.pos++
+		}
+	}
+	if len(documents) == 0 {
+		return nil, ErrEmpty
+	}
+	return documents, nil
+}
+
+func blankLine(line string) bool {
+	line = strings.TrimLeft(line, " \t")
+	return line == "" || line[0] == '#'
+}
+
+func documentMarker(line, marker string) bool {
+	return strings.HasPrefix(line, marker) && (len(line) == 3 || line[3] == ' ' || line[3] == '\t')
+}
+
+func (p *yamlParser) error() error {
+	return fmt.Errorf("line %d: %w", p.pos+1, ErrYAML)
+}
+
+// peek skips blank lines and comments, returning the indentation and content of
+// the next line, unless the document ends
+func (p *yamlParser) peek() (indent int, content string, ok bool) {
+	for ; p.pos < len(p.lines); p.pos++ {
+		line := p.lines[p.pos]
+		if documentMarker(line, "---") || documentMarker(line, "...") {
+			return 0, "", false
+		}
+		if !blankLine(line) {
+			content = strings.TrimLeft(line, " ")
+			return len(line) - len(content), content, true
+		}
+	}
+	return 0, "", false
+}
+
+// parseNode parses the block node on the next lines, which must be indented
+// more than parent to belong to it. The sequence of a mapping value may also be
+// at the same indentation as its key.
+func (p *yamlParser) parseNode(parent int, mappingValue bool) (interface{}, error) {
+	indent, content, ok := p.peek()
+	switch {
+	case ok && indent > parent:
+		return p.parseBlock(parent, indent, content)
+	case ok && indent == parent && mappingValue && sequenceEntry(content):
+		return p.parseSequence(indent)
+	}
+	return nil, nil
+}
+
+func (p *yamlParser) parseBlock(parent, indent int, content string) (interface{}, error) {
+	if content[0] == '\t' {
+		return nil, p.error()
+	}
+	if sequenceEntry(content) {
+		return p.parseSequence(indent)
+	}
+	if _, _, ok := splitKey(content); ok {
+		return p.parseMapping(indent)
+	}
+	p.pos++
+	return p.parseValue(stripComment(content), parent, false)
+}
+
+func sequenceEntry(content string) bool {
+	return content == "-" || strings.HasPrefix(content, "- ") || strings.HasPrefix(content, "-\t")
+}
+
+func (p *yamlParser) parseSequence(indent int) (interface{}, error) {
+	depth++
+	defer func() {
+		depth--
+	}()
+	if depth > maxDepth {
+		return nil, ErrMaxDepth
+	}
+	arr := make([]interface{}, 0)
+	for {
+		i, content, ok := p.peek()
+		if !ok || i < indent {
+			break
+		}
+		if i > indent {
+			return nil, p.error()
+		}
+		if !sequenceEntry(content) {
+			break
+		}
+		rest := strings.TrimLeft(content[1:], " \t")
+		var item interface{}
+		var err error
+		if blankLine(rest) {
+			p.pos++
+			item, err = p.parseNode(indent, false)
+		} else {
+			// the entry continues as a node indented at the column of its content
+			column := indent + len(content) - len(rest)
+			p.lines[p.pos] = strings.Repeat(" ", column) + rest
+			item, err = p.parseBlock(indent, column, rest)
+		}
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, item)
+	}
+	return arr, nil
+}
+
+func (p *yamlParser) parseMapping(indent int) (interface{}, error) {
+	depth++
+	defer func() {
+		depth--
+	}()
+	if depth > maxDepth {
+		return nil, ErrMaxDepth
+	}
+	obj := make(map[string]interface{})
+	var merges []interface{}
+	for {
+		i, content, ok := p.peek()
+		if !ok || i < indent {
+			break
+		}
+		if i > indent {
+			return nil, p.error()
+		}
+		key, rest, ok := splitKey(content)
+		if !ok {
+			if sequenceEntry(content) {
+				break
+			}
+			return nil, p.error()
+		}
+		p.pos++
+		value, err := p.parseValue(rest, indent, true)
+		if err != nil {
+			return nil, err
+		}
+		if key == "<<" {
+			merges = append(merges, value)
+		} else {
+			obj[key] = value
+		}
+	}
+
+	// merged keys, from aliases to other mappings, don't replace explicit ones
+	// and the first mapping merged wins
+	for _, merge := range merges {
+		sources, ok := merge.([]interface{})
+		if !ok {
+			sources = []interface{}{merge}
+		}
+		for _, source := range sources {
+			m, ok := source.(map[string]interface{})
+			if !ok {
+				return nil, p.error()
+			}
+			for k, v := range m {
+				if _, found := obj[k]; !found {
+					obj[k] = v
+				}
+			}
+		}
+	}
+	return obj, nil
+}
+
+// splitKey returns the key and the rest of a line of a block mapping
+func splitKey(content string) (key, rest string, ok bool) {
+	content = stripComment(content)
+	if content == "" || strings.ContainsAny(content[:1], "\t[{|>*&!%@`") ||
+		sequenceEntry(content) || content == "?" || strings.HasPrefix(content, "? ") {
+		return "", "", false
+	}
+	end := 0
+	if content[0] == '"' || content[0] == '\'' {
+		end = closingQuote(content)
+		if end < 0 {
+			return "", "", false
+		}
+		text, err := unquote(content[:end+1])
+		if err != nil {
+			return "", "", false
+		}
+		rest = strings.TrimLeft(content[end+1:], " \t")
+		if !strings.HasPrefix(rest, ":") || len(rest) > 1 && rest[1] != ' ' && rest[1] != '\t' {
+			return "", "", false
+		}
+		return text, strings.TrimLeft(rest[1:], " \t"), true
+	}
+	for end = strings.IndexByte(content, ':'); end >= 0; end = nextIndex(content, ':', end) {
+		if end+1 == len(content) || content[end+1] == ' ' || content[end+1] == '\t' {
+			return strings.TrimRight(content[:end], " \t"), strings.TrimLeft(content[end+1:], " \t"), true
+		}
+	}
+	return "", "", false
+}
+
+func nextIndex(s string, c byte, after int) int {
+	i := strings.IndexByte(s[after+1:], c)
+	if i < 0 {
+		return -1
+	}
+	return after + 1 + i
+}
+
+// stripComment removes a comment at the end of a line, which must be preceded
+// by whitespace and outside of quoted scalars
+func stripComment(line string) string {
+	var inQuote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case inQuote == '"' && c == '\\':
+			i++
+		case inQuote != 0 && c == inQuote:
+			inQuote = 0
+		case inQuote != 0:
+		case (c == '"' || c == '\'') && (i == 0 || strings.IndexByte(" \t[{,:", line[i-1]) >= 0):
+			inQuote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return strings.TrimRight(line[:i], " \t")
+		}
+	}
+	return strings.TrimRight(line, " \t")
+}
+
+// parseValue parses what follows a key or starts a node, with the lines after
+// it when the value continues on them
+func (p *yamlParser) parseValue(text string, parent int, mappingValue bool) (value interface{}, err error) {
+	var anchor, tag string
+	for text != "" && (text[0] == '&' || text[0] == '!') {
+		property, rest, _ := strings.Cut(text, " ")
+		if text[0] == '&' {
+			anchor = property[1:]
+		} else {
+			tag = property
+		}
+		text = strings.TrimLeft(rest, " \t")
+	}
+
+	switch {
+	case text == "":
+		value, err = p.parseNode(parent, mappingValue)
+	case text[0] == '*':
+		if value, err = p.alias(text[1:]); err != nil {
+			return nil, fmt.Errorf("line %d: %w: %s", p.pos, err, text)
+		}
+	case text[0] == '|' || text[0] == '>':
+		value, err = p.parseBlockScalar(text, parent)
+	case text[0] == '[' || text[0] == '{':
+		text, err = p.continueLines(text, parent, func(s string) bool { return flowDepth(s) > 0 })
+		if err == nil {
+			value, err = p.parseFlow(text)
+		}
+	case text[0] == '"' || text[0] == '\'':
+		text, err = p.continueLines(text, parent, func(s string) bool { return closingQuote(s) < 0 })
+		if err == nil {
+			end := closingQuote(text)
+			if end < 0 || end+1 < len(text) {
+				return nil, p.error()
+			}
+			value, err = unquote(text)
+		}
+	default:
+		text, err = p.continueLines(text, parent, nil)
+		if err == nil {
+			value = resolvePlain(text)
+			if tag == "!!str" {
+				value = text
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if anchor != "" {
+		p.anchors[anchor] = value
+	}
+	return value, nil
+}
+
+// alias returns the value of an anchor, counting the nodes it repeats against
+// maxAliasNodes
+func (p *yamlParser) alias(name string) (interface{}, error) {
+	value, found := p.anchors[name]
+	if !found {
+		return nil, ErrAlias
+	}
+	p.aliasNodes += countNodes(value, maxAliasNodes-p.aliasNodes)
+	if p.aliasNodes > maxAliasNodes {
+		return nil, ErrExpansion
+	}
+	return value, nil
+}
+
+// countNodes returns the number of values in value, counting no further once
+// past limit
+func countNodes(value interface{}, limit int) int {
+	count := 1
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, child := range v {
+			if count > limit {
+				break
+			}
+			count += countNodes(child, limit-count)
+		}
+	case []interface{}:
+		for _, child := range v {
+			if count > limit {
+				break
+			}
+			count += countNodes(child, limit-count)
+		}
+	}
+	return count
+}
+
+// continueLines adds the lines indented more than parent to a scalar or flow
+// collection, while incomplete tells it isn't finished or, when nil, for as
+// long as there are such lines. Line breaks are folded into spaces, except for
+// empty lines which are kept.
+func (p *yamlParser) continueLines(text string, parent int, incomplete func(string) bool) (string, error) {
+	quoted := text[0] == '"' || text[0] == '\''
+	breaks := 0
+	for p.pos < len(p.lines) && (incomplete == nil || incomplete(text)) {
+		line := p.lines[p.pos]
+		if documentMarker(line, "---") || documentMarker(line, "...") {
+			break
+		}
+		content := strings.TrimLeft(line, " \t")
+		if content == "" {
+			breaks++
+			p.pos++
+			continue
+		}
+		if incomplete == nil && (len(line)-len(content) <= parent || content[0] == '#') {
+			break
+		}
+		if incomplete == nil {
+			if _, _, ok := splitKey(content); ok {
+				return "", p.error()
+			}
+		}
+		if breaks > 0 {
+			text += strings.Repeat("\n", breaks)
+		} else {
+			text += " "
+		}
+		if !quoted {
+			content = stripComment(content)
+		}
+		text += content
+		breaks = 0
+		p.pos++
+	}
+	if incomplete != nil && incomplete(text) {
+		return "", p.error()
+	}
+	return text, nil
+}
+
+// parseBlockScalar reads a literal or folded block from the lines after the
+// header, which has the style and optional chomping and indentation indicators
+func (p *yamlParser) parseBlockScalar(header string, parent int) (interface{}, error) {
+	literal := header[0] == '|'
+	chomp := byte(0)
+	indent := -1
+	for _, c := range []byte(header[1:]) {
+		switch {
+		case c == '+' || c == '-':
+			chomp = c
+		case c >= '1' && c <= '9':
+			indent = max(parent, 0) + int(c-'0')
+		default:
+			return nil, p.error()
+		}
+	}
+
+	var lines []string
+	for ; p.pos < len(p.lines); p.pos++ {
+		line := p.lines[p.pos]
+		content := strings.TrimLeft(line, " ")
+		if content == "" {
+			lines = append(lines, "")
+			continue
+		}
+		if indent < 0 {
+			indent = len(line) - len(content)
+		}
+		if len(line)-len(content) < indent || indent <= parent || documentMarker(line, "---") || documentMarker(line, "...") {
+			break
+		}
+		lines = append(lines, line[indent:])
+	}
+
+	trailing := 0
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+		trailing++
+	}
+	// folding joins lines with a space, unless they are more indented or
+	// separated by empty lines, which are kept as line breaks
+	var sb strings.Builder
+	breaks := 0
+	started, previousNormal := false, false
+	for _, line := range lines {
+		if line == "" {
+			breaks++
+			continue
+		}
+		normal := line[0] != ' ' && line[0] != '\t'
+		switch {
+		case !started:
+			sb.WriteString(strings.Repeat("\n", breaks))
+		case !literal && previousNormal && normal && breaks == 0:
+			sb.WriteByte(' ')
+		case !literal && previousNormal && normal:
+			sb.WriteString(strings.Repeat("\n", breaks))
+		default:
+			sb.WriteString(strings.Repeat("\n", breaks+1))
+		}
+		sb.WriteString(line)
+		breaks, started, previousNormal = 0, true, normal
+	}
+	switch {
+	case !started && chomp == '+':
+		sb.WriteString(strings.Repeat("\n", trailing))
+	case !started || chomp == '-':
+	case chomp == '+':
+		sb.WriteString(strings.Repeat("\n", trailing+1))
+	default:
+		sb.WriteByte('\n')
+	}
+	return sb.String(), nil
+}
+
+// closingQuote returns the position of the quote ending the scalar starting
+// text, or -1
+func closingQuote(text string) int {
+	q := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case q == '"' && text[i] == '\\':
+			i++
+		case text[i] == q && q == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			i++
+		case text[i] == q:
+			return i
+		}
+	}
+	return -1
+}
+
+// unquote decodes a single or double quoted scalar, the latter allowing the
+// escapes of YAML on top of the ones of JSON
+func unquote(text string) (string, error) {
+	if text[0] == '\'' {
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	}
+	var sb strings.Builder
+	for i := 1; i < len(text)-1; i++ {
+		c := text[i]
+		if c != '\\' {
+			sb.WriteByte(c)
+			continue
+		}
+		i++
+		if i >= len(text)-1 {
+			return "", ErrYAML
+		}
+		size := 0
+		switch text[i] {
+		case '0':
+			sb.WriteByte(0)
+		case 'a':
+			sb.WriteByte('\a')
+		case 'b':
+			sb.WriteByte('\b')
+		case 't', '\t':
+			sb.WriteByte('\t')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'v':
+			sb.WriteByte('\v')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'e':
+			sb.WriteByte(0x1b)
+		case ' ', '"', '/', '\\':
+			sb.WriteByte(text[i])
+		case 'N':
+			sb.WriteRune('\u0085')
+		case '_':
+			sb.WriteRune('\u00a0')
+		case 'L':
+			sb.WriteRune('\u2028')
+		case 'P':
+			sb.WriteRune('\u2029')
+		case 'x':
+			size = 2
+		case 'u':
+			size = 4
+		case 'U':
+			size = 8
+		default:
+			return "", ErrYAML
+		}
+		if size > 0 {
+			if i+size >= len(text) {
+				return "", ErrYAML
+			}
+			code, err := strconv.ParseUint(text[i+1:i+1+size], 16, 32)
+			if err != nil {
+				return "", ErrYAML
+			}
+			if size == 4 && code >= 0xd800 && code < 0xdc00 && strings.HasPrefix(text[i+5:], `\u`) {
+				// a surrogate pair, as JSON writes characters outside of the BMP
+				low, err := strconv.ParseUint(text[i+7:min(i+11, len(text))], 16, 32)
+				if err == nil && low >= 0xdc00 && low < 0xe000 {
+					code = 0x10000 + (code-0xd800)<<10 + low - 0xdc00
+					i += 6
+				}
+			}
+			sb.WriteRune(rune(code))
+			i += size
+		}
+	}
+	return sb.String(), nil
+}
+
+// flowDepth returns how many flow collections are left open in text
+func flowDepth(text string) int {
+	depth := 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+		case '"', '\'':
+			if i > 0 && strings.IndexByte(" \t[{,:", text[i-1]) < 0 {
+				continue
+			}
+			end := closingQuote(text[i:])
+			if end < 0 {
+				return depth + 1
+			}
+			i += end
+		}
+	}
+	return depth
+}
+
+func (p *yamlParser) parseFlow(text string) (interface{}, error) {
+	f := &yamlFlow{text: text, parser: p}
+	value, err := f.value()
+	if err == nil {
+		f.skipSpace()
+		if f.pos < len(f.text) {
+			err = ErrYAML
+		}
+	}
+	if err != nil {
+		if !errors.Is(err, ErrMaxDepth) && !errors.Is(err, ErrExpansion) {
+			err = ErrYAML
+		}
+		return nil, fmt.Errorf("line %d: %w", p.pos, err)
+	}
+	return value, nil
+}
+
+// yamlFlow parses a flow collection, which may have been joined from several
+// lines
+type yamlFlow struct {
+	text   string
+	pos    int
+	parser *yamlParser
+}
+
+func (f *yamlFlow) skipSpace() {
+	for f.pos < len(f.text) && strings.IndexByte(" \t\n", f.text[f.pos]) >= 0 {
+		f.pos++
+	}
+}
+
+func (f *yamlFlow) value() (interface{}, error) {
+	f.skipSpace()
+	if f.pos >= len(f.text) {
+		return nil, ErrYAML
+	}
+	var anchor string
+	if f.text[f.pos] == '&' {
+		start := f.pos + 1
+		for f.pos < len(f.text) && strings.IndexByte(" \t\n,[]{}", f.text[f.pos]) < 0 {
+			f.pos++
+		}
+		anchor = f.text[start:f.pos]
+		f.skipSpace()
+		if anchor == "" || f.pos >= len(f.text) || strings.IndexByte(",]}", f.text[f.pos]) >= 0 {
+			return nil, ErrYAML
+		}
+	}
+	var value interface{}
+	var err error
+	switch c := f.text[f.pos]; c {
+	case '[':
+		value, err = f.sequence()
+	case '{':
+		value, err = f.mapping()
+	case '"', '\'':
+		end := closingQuote(f.text[f.pos:])
+		if end < 0 {
+			return nil, ErrYAML
+		}
+		value, err = unquote(f.text[f.pos : f.pos+end+1])
+		f.pos += end + 1
+	default:
+		text := f.plain()
+		if strings.HasPrefix(text, "*") {
+			if value, err = f.parser.alias(text[1:]); err != nil {
+				return nil, err
+			}
+		} else {
+			value = resolvePlain(text)
+		}
+	}
+	if anchor != "" {
+		f.parser.anchors[anchor] = value
+	}
+	return value, err
+}
+
+// plain reads a plain scalar, which ends at an indicator of the flow style
+func (f *yamlFlow) plain() string {
+	start := f.pos
+	for ; f.pos < len(f.text); f.pos++ {
+		c := f.text[f.pos]
+		if c == ',' || c == '[' || c == ']' || c == '{' || c == '}' ||
+			c == ':' && (f.pos+1 == len(f.text) || strings.IndexByte(" \t,[]{}", f.text[f.pos+1]) >= 0) {
+			break
+		}
+	}
+	return strings.TrimRight(f.text[start:f.pos], " \t\n")
+}
+
+func (f *yamlFlow) sequence() (interface{}, error) {
+	depth++
+	defer func() {
+		depth--
+	}()
+	if depth > maxDepth {
+		return nil, ErrMaxDepth
+	}
+	arr := make([]interface{}, 0)
+	f.pos++
+	for {
+		f.skipSpace()
+		if f.pos < len(f.text) && f.text[f.pos] == ']' {
+			f.pos++
+			return arr, nil
+		}
+		item, err := f.value()
+		if err != nil {
+			return nil, err
+		}
+		f.skipSpace()
+		if f.pos < len(f.text) && f.text[f.pos] == ':' {
+			// a single pair mapping
+			key, ok := item.(string)
+			if !ok {
+				key = marshal(item)
+			}
+			f.pos++
+			value, err := f.value()
+			if err != nil {
+				return nil, err
+			}
+			item = map[string]interface{}{key: value}
+			f.skipSpace()
+		}
+		arr = append(arr, item)
+		if f.pos < len(f.text) && f.text[f.pos] == ',' {
+			f.pos++
+		} else if f.pos >= len(f.text) || f.text[f.pos] != ']' {
+			return nil, ErrYAML
+		}
+	}
+}
+
+func (f *yamlFlow) mapping() (interface{}, error) {
+	depth++
+	defer func() {
+		depth--
+	}()
+	if depth > maxDepth {
+		return nil, ErrMaxDepth
+	}
+	obj := make(map[string]interface{})
+	f.pos++
+	for {
+		f.skipSpace()
+		if f.pos < len(f.text) && f.text[f.pos] == '}' {
+			f.pos++
+			return obj, nil
+		}
+		var key string
+		if f.pos < len(f.text) && (f.text[f.pos] == '"' || f.text[f.pos] == '\'') {
+			end := closingQuote(f.text[f.pos:])
+			if end < 0 {
+				return nil, ErrYAML
+			}
+			var err error
+			if key, err = unquote(f.text[f.pos : f.pos+end+1]); err != nil {
+				return nil, err
+			}
+			f.pos += end + 1
+		} else {
+			key = f.plain()
+		}
+		f.skipSpace()
+		var value interface{}
+		if f.pos < len(f.text) && f.text[f.pos] == ':' {
+			f.pos++
+			f.skipSpace()
+			if f.pos < len(f.text) && f.text[f.pos] != ',' && f.text[f.pos] != '}' {
+				var err error
+				if value, err = f.value(); err != nil {
+					return nil, err
+				}
+			}
+		}
+		obj[key] = value
+		f.skipSpace()
+		if f.pos < len(f.text) && f.text[f.pos] == ',' {
+			f.pos++
+		} else if f.pos >= len(f.text) || f.text[f.pos] != '}' {
+			return nil, ErrYAML
+		}
+	}
+}
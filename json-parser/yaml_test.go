@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestYAMLRoundTrip(t *testing.T) {
+	files, _ := filepath.Glob("json_checker/pass*.json")
+	steps, _ := filepath.Glob("tests/*/valid*.json")
+	for _, name := range append(files, steps...) {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := parse(string(data))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		documents, err := parseYAML(strings.NewReader(marshalYAML(want)))
+		if err != nil {
+			t.Errorf("%s: %v\n%s", name, err, marshalYAML(want))
+			continue
+		}
+		if got := marshal(documents[0]); len(documents) != 1 || got != marshal(want) {
+			t.Errorf("%s: want: %s - got: %s", name, marshal(want), got)
+		}
+	}
+}
+
+func TestParseYAML(t *testing.T) {
+	testCases := map[string]string{
+		"a: 1\nb: [x, 'y''s', {c: null}]\n":             `[{"a":1,"b":["x","y's",{"c":null}]}]`,
+		"- &x {a: 1}\n- <<: *x\n  b: 2\n":               `[[{"a":1},{"a":1,"b":2}]]`,
+		"k:\n- a\n- b # comment\nz: ~\n":                `[{"k":["a","b"],"z":null}]`,
+		"t: |\n  one\n    two\n\nf: >-\n  a\n  b\n":     `[{"f":"a b","t":"one\n  two\n"}]`,
+		"--- 1\n--- two\n...\n---\n- true\n":            `[1,"two",[true]]`,
+		"x: [&a [1], *a, {b: &c 2}, *c]\n":              `[{"x":[[1],[1],{"b":2},2]}]`,
+		"s: !!str 010\nn: 010\nh: 0x10\nq: \"\\u00e9\"": `[{"h":16,"n":10,"q":"é","s":"010"}]`,
+	}
+	for input, want := range testCases {
+		documents, err := parseYAML(strings.NewReader(input))
+		if err != nil {
+			t.Errorf("%q: %v", input, err)
+		} else if got := marshal(documents); got != want {
+			t.Errorf("%q: want: %s - got: %s", input, want, got)
+		}
+	}
+	for _, input := range []string{"a: *b\n", "a: 1\n  b: 2\n", "a: \"x\n", "[1, 2\n", "a:\n\tb: 1\n",
+		"x: [&a]\n", "x: {a: &b}\n", "x: [&]\n", "x: [*]\n", "x: [&a, 1]\n"} {
+		if _, err := parseYAML(strings.NewReader(input)); err == nil {
+			t.Errorf("%q: expected an error", input)
+		}
+	}
+}
+
+func TestYAMLLimits(t *testing.T) {
+	saved := maxDepth
+	maxDepth = 3
+	defer func() {
+		maxDepth = saved
+	}()
+	testCases := map[string]error{
+		"a:\n  b:\n    - 1\n":          nil,
+		"a:\n  b:\n    c:\n      d: 1": ErrMaxDepth,
+		"- - - - 1\n":                  ErrMaxDepth,
+		"a: [[{b: 1}]]\n":              ErrMaxDepth,
+		"a: {b: [1]}\n":                nil,
+	}
+	for input, want := range testCases {
+		if _, err := parseYAML(strings.NewReader(input)); !errors.Is(err, want) {
+			t.Errorf("%q: want: %v - got: %v", input, want, err)
+		}
+		if depth != 0 {
+			t.Fatalf("%q: depth left at %d", input, depth)
+		}
+	}
+	maxDepth = saved
+
+	// each level repeats the one before ten times
+	var laughs strings.Builder
+	laughs.WriteString("a: &a [lol, lol, lol, lol, lol, lol, lol, lol, lol, lol]\n")
+	for c := 'b'; c <= 'i'; c++ {
+		fmt.Fprintf(&laughs, "%c: &%c [%s]\n", c, c, strings.Repeat(fmt.Sprintf("*%c, ", c-1), 9)+fmt.Sprintf("*%c", c-1))
+	}
+	if _, err := parseYAML(strings.NewReader(laughs.String())); !errors.Is(err, ErrExpansion) {
+		t.Errorf("block aliases: want: %v - got: %v", ErrExpansion, err)
+	}
+	flow := "{" + strings.ReplaceAll(strings.TrimSuffix(laughs.String(), "\n"), "\n", ", ") + "}"
+	if _, err := parseYAML(strings.NewReader(flow)); !errors.Is(err, ErrExpansion) {
+		t.Errorf("flow aliases: want: %v - got: %v", ErrExpansion, err)
+	}
+}
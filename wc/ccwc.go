@@ -1,15 +1,32 @@
 package main
 
 import (
-	"bufio"
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
 	"unicode"
+	"unicode/utf8"
 )
 
 var countBytes, countLines, countWords, countChars, maxLengths, displayHelp bool
+var jobs int
+var files0From, totalMode string
+
+// numberWidth is the width of the columns, found as GNU wc does from the size
+// of the files
+var numberWidth int
+
+var (
+	ErrEmptyName = errors.New("invalid zero-length file name")
+	ErrStdinName = errors.New("when reading file names from standard input, no file name of '-' allowed")
+)
 
 func init() {
 	flag.BoolVar(&displayHelp, "h", false, "display this help and exit")
@@ -24,10 +41,31 @@ func init() {
 	flag.BoolVar(&countLines, "lines", false, "print the newline counts")
 	flag.BoolVar(&maxLengths, "max-line-length", false, "print the maximum display width")
 	flag.BoolVar(&countWords, "words", false, "print the word counts")
+	flag.IntVar(&jobs, "j", runtime.NumCPU(), "count up to `n` files, and parts of each large file, at the same time")
+	flag.IntVar(&jobs, "jobs", runtime.NumCPU(), "count up to `n` files, and parts of each large file, at the same time")
+	flag.StringVar(&files0From, "files0-from", "", "read the names of the files from `file`, separated by NUL characters, - for the standard input")
+	flag.StringVar(&totalMode, "total", "auto", "print the total `when`: auto (for more than one file), always, only or never")
+	flag.StringVar(&outputFormat, "format", "text", "print the counts as `text`, or as records in json, csv or tsv")
+	flag.BoolVar(&recursive, "r", false, "count the files in the directories given, recursively")
+	flag.BoolVar(&recursive, "recursive", false, "count the files in the directories given, recursively")
+	flag.Var(&includes, "include", "with -r, count only the files matching `pattern`, on their name or on their path with a slash")
+	flag.Var(&excludes, "exclude", "with -r, skip the files and directories matching `pattern`")
+	flag.StringVar(&ignoreFile, "ignore-file", ".gitignore", "with -r, skip what the files with this `name` list, as git does; empty to skip nothing")
+	flag.StringVar(&followLinks, "follow", "args", "follow symbolic links `when`: never, args (only the names given) or always")
+	flag.StringVar(&groupBy, "group", "", "print the totals per `group`: dir, ext or lang, instead of per file")
+	flag.BoolVar(&countCode, "code", false, "print the blank, comment and code lines of source files, and the totals per language")
+	flag.IntVar(&freqTop, "freq", 0, "print the `n` most frequent words after the counts")
+	flag.StringVar(&distinctMode, "distinct", "", "print the distinct words, counted as `mode` says: exact, or hll for an estimate and the bound of its standard error")
+	flag.IntVar(&hllPrecision, "precision", 14, "with --distinct=hll, use 2^`p` registers, from 4 to 18")
+	flag.BoolVar(&foldCase, "fold", false, "with --freq or --distinct, count words in lower case")
+	flag.BoolVar(&stripPunct, "strip-punct", false, "with --freq or --distinct, strip the punctuation around words")
+	flag.StringVar(&stopwordsFile, "stopwords", "", "with --freq or --distinct, leave out the words listed in `file`")
 }
 
 type counters struct {
 	bytes, lines, words, chars, maxLineLength int64
+	blank, comment, code                      int64
+	distinct                                  distinctWords
 }
 
 func main() {
@@ -41,122 +79,610 @@ func main() {
 		countBytes, countLines, countWords = true, true, true
 	}
 
-	var fileCount int64
-	var total counters
+	switch totalMode {
+	case "auto", "always", "only", "never":
+	default:
+		fmt.Fprintf(os.Stderr, "invalid argument %q for --total\n", totalMode)
+		os.Exit(1)
+	}
+	switch {
+	case followLinks != "never" && followLinks != "args" && followLinks != "always":
+		fmt.Fprintf(os.Stderr, "invalid argument %q for --follow\n", followLinks)
+		os.Exit(1)
+	case groupBy != "" && groupBy != "dir" && groupBy != "ext" && groupBy != "lang":
+		fmt.Fprintf(os.Stderr, "invalid argument %q for --group\n", groupBy)
+		os.Exit(1)
+	case distinctMode != "" && distinctMode != "exact" && distinctMode != "hll":
+		fmt.Fprintf(os.Stderr, "invalid argument %q for --distinct\n", distinctMode)
+		os.Exit(1)
+	case hllPrecision < 4 || hllPrecision > 18:
+		fmt.Fprintf(os.Stderr, "invalid argument %d for --precision\n", hllPrecision)
+		os.Exit(1)
+	}
+	if freqTop > 0 && outputFormat != "text" {
+		fmt.Fprintln(os.Stderr, "--freq prints only as text")
+		os.Exit(1)
+	}
+	if stopwordsFile != "" {
+		if err := loadStopwords(stopwordsFile); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err := startOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	names := flag.Args()
+	readStdin := len(names) == 0 && files0From == "" && !recursive
+	regular := true
+	if files0From != "" {
+		if flag.NArg() > 0 {
+			fmt.Fprintf(os.Stderr, "extra operand %q\nfile operands cannot be combined with --files0-from\n", flag.Arg(0))
+			os.Exit(1)
+		}
+		var err error
+		if names, regular, err = readFileNames(files0From); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+	if recursive {
+		if len(names) == 0 && files0From == "" {
+			names = []string{"."}
+		}
+		names = expandNames(names)
+	}
+	switch {
+	case !regular:
+		numberWidth = 1
+	case readStdin:
+		numberWidth = findNumberWidth([]string{"-"})
+	default:
+		numberWidth = findNumberWidth(names)
+	}
+
+	fileCount := int64(len(names))
+	total := processFiles(names)
+
+	if readStdin {
+		result, err := countFile(os.Stdin)
+		reportResult(fileResult{result, true, err}, "")
+		total, fileCount = result, 1
+	}
+
+	displayTotals(fileCount, total)
+
+	if freqTop > 0 {
+		printFrequencies()
+	}
+}
+
+// readFileNames reads the names separated by NUL characters, telling if they
+// come from a regular file
+func readFileNames(filename string) (names []string, regular bool, err error) {
+	file := os.Stdin
+	if filename != "-" {
+		if file, err = os.Open(filename); err != nil {
+			return nil, false, err
+		}
+		defer file.Close()
+	}
+	if stat, err := file.Stat(); err == nil {
+		regular = stat.Mode().IsRegular()
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(data) == 0 {
+		return nil, regular, nil
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\x00"), "\x00"), regular, nil
+}
 
-	for _, name := range flag.Args() {
-		var result counters
+// findNumberWidth gives the columns room for the total size of the files when
+// all of them are regular, and at least 7 otherwise. A single count of a
+// single file takes no padding.
+func findNumberWidth(names []string) int {
+	selected := 0
+	for _, count := range []bool{countLines, countWords, countChars, countBytes, maxLengths} {
+		if count {
+			selected++
+		}
+	}
+	if len(names) == 1 && selected == 1 {
+		return 1
+	}
+	width, minimum := 1, 1
+	var size int64
+	for _, name := range names {
+		var stat os.FileInfo
+		var err error
 		if name == "-" {
-			result = processFile("", os.Stdin)
+			stat, err = os.Stdin.Stat()
 		} else {
-			result = processFilename(name)
+			stat, err = os.Stat(name)
+		}
+		switch {
+		case err != nil:
+		case stat.Mode().IsRegular():
+			size += stat.Size()
+		default:
+			minimum = 7
 		}
-		total.lines += result.lines
-		total.words += result.words
-		total.chars += result.chars
-		total.bytes += result.bytes
-		total.maxLineLength = max(total.maxLineLength, result.maxLineLength)
-		fileCount++
 	}
+	for ; size >= 10; size /= 10 {
+		width++
+	}
+	return max(width, minimum)
+}
 
-	displayTotals(fileCount, total)
+type fileResult struct {
+	counters
+	opened bool
+	err    error
+}
 
-	if fileCount == 0 {
-		processFile("", os.Stdin)
+// processFiles counts the files on a pool of workers, printing their counts in
+// the order of the names as soon as they are available. The standard input,
+// named -, is read in order apart from the pool, as reading it again finds
+// nothing.
+func processFiles(names []string) (total counters) {
+	results := make([]chan fileResult, len(names))
+	for i := range results {
+		results[i] = make(chan fileResult, 1)
+	}
+	indexes := make(chan int)
+	go func() {
+		for i, name := range names {
+			if name != "-" {
+				indexes <- i
+			}
+		}
+		close(indexes)
+	}()
+	for w := 0; w < max(jobs, 1); w++ {
+		go func() {
+			for i := range indexes {
+				results[i] <- countFilename(names[i])
+			}
+		}()
 	}
+	go func() {
+		for i, name := range names {
+			if name == "-" {
+				results[i] <- countFilename(name)
+			}
+		}
+	}()
+
+	groups := map[string]*counters{}
+	for i, name := range names {
+		result := <-results[i]
+		if groupBy == "" {
+			reportResult(result, name)
+		} else if result.err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", result.err)
+		}
+		if !result.opened {
+			continue
+		}
+		total.add(result.counters)
+		// with --code, the totals per language follow the files
+		key := groupKey(name)
+		if lang := languageOf(name); groupBy == "" && countCode && lang != nil {
+			key = lang.name
+		}
+		if key != "" {
+			if groups[key] == nil {
+				groups[key] = &counters{}
+			}
+			groups[key].add(result.counters)
+		}
+	}
+
+	if totalMode != "only" {
+		keys := make([]string, 0, len(groups))
+		for key := range groups {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			printResult(fileResult{counters: *groups[key], opened: true}, key, "group")
+		}
+	}
+	return
 }
 
-func processFilename(name string) (result counters) {
+func (c *counters) add(other counters) {
+	c.lines += other.lines
+	c.words += other.words
+	c.chars += other.chars
+	c.bytes += other.bytes
+	c.maxLineLength = max(c.maxLineLength, other.maxLineLength)
+	c.blank += other.blank
+	c.comment += other.comment
+	c.code += other.code
+	if other.distinct != nil {
+		if c.distinct == nil {
+			c.distinct = newDistinctWords()
+		}
+		c.distinct.merge(other.distinct)
+	}
+}
+
+func countFilename(name string) (result fileResult) {
+	switch {
+	case name == "":
+		result.err = ErrEmptyName
+		return
+	case name == "-" && files0From == "-":
+		result.err = ErrStdinName
+		return
+	case name == "-":
+		result.counters, result.err = countFile(os.Stdin)
+		result.opened = true
+		return
+	}
 	file, err := os.Open(name)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
+		result.err = err
 		return
 	}
-	result = processFile(name, file)
+	result.counters, result.err = countFile(file)
+	result.opened = true
 	file.Close()
 	return
 }
 
-func processFile(name string, file *os.File) (result counters) {
-	var lineLength int64
-	var prev, curr rune
-	var bytesRead int
-	var err error
+// countFile counts the whole file, taking only the size of regular files when
+// nothing else is needed
+func countFile(file *os.File) (counters, error) {
+	if stat, err := file.Stat(); err == nil && stat.Mode().IsRegular() {
+		if !countLines && !countWords && !countChars && !maxLengths && !readInOrder() {
+			return counters{bytes: stat.Size()}, nil
+		}
+		if parts := min(int64(jobs), stat.Size()/minPartSize); parts > 1 && !readInOrder() {
+			return countParallel(file, stat.Size(), int(parts))
+		}
+	}
+	var lang *language
+	if countCode {
+		lang = languageOf(file.Name())
+	}
+	c, err := countReader(file, lang)
+	return c.counters, err
+}
 
-	reader := bufio.NewReaderSize(file, 1024*1024)
+// readInOrder tells if the counts need the whole file read from the start, as
+// the lines of code and the words collected do
+func readInOrder() bool {
+	return countCode || freqTop > 0 || distinctMode != ""
+}
 
-	prev = ' '
+// countReader counts all that is read, and the kinds of lines of a source file
+// when its language is given
+func countReader(reader io.Reader, lang *language) (c counter, err error) {
+	if lang != nil {
+		c.code = &codeCounter{lang: lang}
+	}
+	if freqTop > 0 || distinctMode != "" {
+		c.collector = &wordCollector{}
+		if freqTop > 0 {
+			c.collector.counts = map[string]int64{}
+		}
+		if distinctMode != "" {
+			c.collector.distinct = newDistinctWords()
+		}
+	}
+	buffer := make([]byte, 256*1024)
 	for {
-		curr, bytesRead, err = reader.ReadRune()
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			panic(err)
+		n, err := reader.Read(buffer)
+		c.write(buffer[:n])
+		if err == io.EOF {
+			break
 		}
-		if !unicode.IsSpace(curr) && unicode.IsSpace(prev) {
-			result.words++
+		if err != nil {
+			c.result()
+			return c, err
 		}
-		if curr == '\t' {
-			// adjust for tab stops
-			lineLength = (lineLength + 8) / 8 * 8
-		} else if curr == '\n' {
-			result.lines++
-			lineLength = 0
-		} else if curr != '\r' {
-			lineLength++
+	}
+	c.result()
+	return c, nil
+}
+
+const minPartSize = 64 * 1024
+
+// countParallel splits a file in parts counted at the same time, then merges
+// their counts in order
+func countParallel(file io.ReaderAt, size int64, parts int) (counters, error) {
+	offsets := []int64{0}
+	for i := 1; i < parts; i++ {
+		offset := splitOffset(file, size*int64(i)/int64(parts))
+		if offset > offsets[len(offsets)-1] && offset < size {
+			offsets = append(offsets, offset)
 		}
-		result.chars++
-		result.bytes += int64(bytesRead)
-		result.maxLineLength = max(result.maxLineLength, lineLength)
-		prev = curr
 	}
+	offsets = append(offsets, size)
+
+	results := make([]counter, len(offsets)-1)
+	startsWord := make([]bool, len(results))
+	errs := make([]error, len(results))
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			start, end := offsets[i], offsets[i+1]
+			startsWord[i] = isWordStart(file, start)
+			results[i], errs[i] = countReader(io.NewSectionReader(file, start, end-start), nil)
+		}(i)
+	}
+	wg.Wait()
 
-	if file != os.Stdin {
-		stat, err := file.Stat()
+	total := results[0]
+	for i := 1; i < len(results); i++ {
+		total.merge(&results[i], startsWord[i])
+	}
+	for _, err := range errs {
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "%v\n", err)
-			return
+			return total.counters, err
 		}
-		result.bytes = stat.Size()
 	}
+	return total.counters, nil
+}
+
+// splitOffset moves an offset forward past the bytes that may continue a
+// character started before it, at most 3, to where the sequential count would
+// decode a character
+func splitOffset(file io.ReaderAt, offset int64) int64 {
+	var buffer [utf8.UTFMax - 1]byte
+	n, _ := file.ReadAt(buffer[:], offset)
+	for i := 0; i < n && !utf8.RuneStart(buffer[i]); i++ {
+		offset++
+	}
+	return offset
+}
+
+// isWordStart tells if the character at offset is not a space
+func isWordStart(file io.ReaderAt, offset int64) bool {
+	var buffer [utf8.UTFMax]byte
+	n, _ := file.ReadAt(buffer[:], offset)
+	r, _ := utf8.DecodeRune(buffer[:n])
+	return !unicode.IsSpace(r)
+}
+
+const (
+	classWord byte = iota
+	classControl
+	classSpace
+	classVerticalTab
+	classNewline
+	classTab
+	classReturn
+)
+
+// asciiClass tells how each ASCII byte affects the counts. Control characters
+// are part of words but take no width, and a carriage return or form feed
+// starts the line width over.
+var asciiClass = func() (classes [utf8.RuneSelf]byte) {
+	for b := range classes {
+		if runeWidth(rune(b)) == 0 {
+			classes[b] = classControl
+		}
+	}
+	classes[' '] = classSpace
+	classes['\v'] = classVerticalTab
+	classes['\n'] = classNewline
+	classes['\t'] = classTab
+	classes['\r'] = classReturn
+	classes['\f'] = classReturn
+	return
+}()
+
+// spaceLead has the first bytes of the encodings of all the non ASCII spaces,
+// so that counting words decodes only the characters starting with them
+var spaceLead = [256]bool{0xc2: true, 0xe1: true, 0xe2: true, 0xe3: true}
+
+// counter counts a file as it is read in chunks, keeping what is needed
+// between them: whether a word or a line is in progress, and the start of a
+// character split across chunks. To merge the count of the part of a file that
+// follows, it also keeps the first line of the part as if it started the file:
+// its width and the width before its first tab.
+type counter struct {
+	counters
+	inWord     bool
+	lineLength int64
+	pending    []byte
+
+	sawLineEnd, headTab   bool
+	headLength, headTabAt int64
+
+	code      *codeCounter
+	collector *wordCollector
+}
+
+// merge adds the count of the part following the one counted, startsWord
+// telling if it starts with a word that may continue the last one
+func (c *counter) merge(next *counter, startsWord bool) {
+	c.bytes += next.bytes
+	c.lines += next.lines
+	c.words += next.words
+	c.chars += next.chars
+	if c.inWord && startsWord {
+		c.words--
+	}
+	c.inWord = next.inWord
 
-	if countLines {
-		fmt.Printf("%7d ", result.lines)
+	headLength := next.lineLength
+	if next.sawLineEnd {
+		headLength = next.headLength
 	}
-	if countWords {
-		fmt.Printf("%7d ", result.words)
+	// the first line of the part continues the last line counted, and its
+	// tabs after the first keep their stops from there
+	if next.headTab {
+		headLength += (c.lineLength+next.headTabAt+8)/8*8 - (next.headTabAt+8)/8*8
+	} else {
+		headLength += c.lineLength
 	}
-	if countChars {
-		fmt.Printf("%7d ", result.chars)
+	c.maxLineLength = max(c.maxLineLength, next.maxLineLength, headLength)
+	if next.sawLineEnd {
+		c.lineLength = next.lineLength
+	} else {
+		c.lineLength = headLength
 	}
-	if countBytes {
-		fmt.Printf("%7d ", result.bytes)
+}
+
+func (c *counter) write(data []byte) {
+	c.bytes += int64(len(data))
+	if c.code != nil {
+		c.code.write(data, &c.counters)
 	}
-	if maxLengths {
-		fmt.Printf("%7d ", result.maxLineLength)
+	if c.collector != nil {
+		c.collector.write(data)
 	}
-	fmt.Printf("%s\n", name)
-	return
+	if !countWords && !countChars && !maxLengths {
+		c.lines += int64(bytes.Count(data, []byte{'\n'}))
+		return
+	}
+	if len(c.pending) > 0 {
+		data = append(c.pending, data...)
+		c.pending = nil
+	}
+	c.scan(data, false)
 }
 
-func displayTotals(fileCount int64, total counters) {
-	if fileCount > 1 {
-		if countLines {
-			fmt.Printf("%7d ", total.lines)
+// result finishes the count, taking the bytes left of an incomplete character
+// as invalid
+func (c *counter) result() counters {
+	if c.code != nil {
+		c.code.finish(&c.counters)
+		c.code = nil
+	}
+	if c.collector != nil {
+		c.collector.finish()
+		c.distinct = c.collector.distinct
+		c.collector = nil
+	}
+	if len(c.pending) > 0 {
+		c.scan(c.pending, true)
+		c.pending = nil
+	}
+	c.maxLineLength = max(c.maxLineLength, c.lineLength)
+	return c.counters
+}
+
+// scan goes through data a byte at a time, decoding characters only when a
+// byte outside of ASCII appears and it needs to: always for -m and -L, and for
+// -w only when it could start a space
+func (c *counter) scan(data []byte, final bool) {
+	decode := countChars || maxLengths
+	for i := 0; i < len(data); {
+		b := data[i]
+		if b < utf8.RuneSelf {
+			switch asciiClass[b] {
+			case classWord:
+				if !c.inWord {
+					c.words++
+					c.inWord = true
+				}
+				c.lineLength++
+			case classControl:
+				if !c.inWord {
+					c.words++
+					c.inWord = true
+				}
+			case classSpace:
+				c.inWord = false
+				c.lineLength++
+			case classVerticalTab:
+				c.inWord = false
+			case classNewline:
+				c.inWord = false
+				c.lines++
+				c.endLine()
+			case classTab:
+				// adjust for tab stops
+				if !c.sawLineEnd && !c.headTab {
+					c.headTab = true
+					c.headTabAt = c.lineLength
+				}
+				c.inWord = false
+				c.lineLength = (c.lineLength + 8) / 8 * 8
+			case classReturn:
+				c.inWord = false
+				c.endLine()
+			}
+			c.chars++
+			i++
+			continue
 		}
-		if countWords {
-			fmt.Printf("%7d ", total.words)
+		if !decode && !spaceLead[b] {
+			if !c.inWord {
+				c.words++
+				c.inWord = true
+			}
+			i++
+			continue
+		}
+		if !final && !utf8.FullRune(data[i:]) {
+			c.pending = append([]byte(nil), data[i:]...)
+			return
 		}
-		if countChars {
-			fmt.Printf("%7d ", total.chars)
+		r, size := utf8.DecodeRune(data[i:])
+		if unicode.IsSpace(r) {
+			c.inWord = false
+		} else if !c.inWord {
+			c.words++
+			c.inWord = true
 		}
-		if countBytes {
-			fmt.Printf("%7d ", total.bytes)
+		c.chars++
+		if maxLengths && size > 1 {
+			c.lineLength += int64(runeWidth(r))
 		}
-		if maxLengths {
-			fmt.Printf("%7d ", total.maxLineLength)
+		i += size
+	}
+}
+
+func (c *counter) endLine() {
+	if !c.sawLineEnd {
+		c.sawLineEnd = true
+		c.headLength = c.lineLength
+	}
+	c.maxLineLength = max(c.maxLineLength, c.lineLength)
+	c.lineLength = 0
+}
+
+func printCounters(result counters, name string) {
+	var columns []string
+	for _, column := range columnsOf(result) {
+		if column.selected {
+			columns = append(columns, fmt.Sprintf("%*d", numberWidth, column.count))
 		}
-		fmt.Printf("total\n")
+	}
+	if name != "" {
+		columns = append(columns, name)
+	}
+	fmt.Println(strings.Join(columns, " "))
+}
+
+// reportResult prints the result of a file, or only its error with
+// --total=only
+func reportResult(result fileResult, name string) {
+	if totalMode != "only" {
+		printResult(result, name, "file")
+	} else if result.err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", result.err)
+	}
+}
+
+func displayTotals(fileCount int64, total counters) {
+	switch {
+	case totalMode == "only" && outputFormat == "text":
+		printCounters(total, "")
+	case totalMode == "only", totalMode == "always", totalMode == "auto" && fileCount > 1:
+		printResult(fileResult{counters: total, opened: true}, "total", "total")
 	}
 }
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+// referenceCount counts a rune at a time, as ccwc used to
+func referenceCount(data []byte) (result counters) {
+	reader := bufio.NewReader(bytes.NewReader(data))
+	var prev rune = ' '
+	var lineLength int64
+	for {
+		r, size, err := reader.ReadRune()
+		if err == io.EOF {
+			break
+		}
+		result.bytes += int64(size)
+		result.chars++
+		if r == '\n' {
+			result.lines++
+		}
+		if unicode.IsSpace(prev) && !unicode.IsSpace(r) {
+			result.words++
+		}
+		switch {
+		case r == '\t':
+			lineLength = (lineLength + 8) / 8 * 8
+		case r == '\n', r == '\r', r == '\f':
+			result.maxLineLength = max(result.maxLineLength, lineLength)
+			lineLength = 0
+		case r != utf8.RuneError || size > 1:
+			lineLength += int64(runeWidth(r))
+		}
+		prev = r
+	}
+	result.maxLineLength = max(result.maxLineLength, lineLength)
+	return
+}
+
+func setCounts(lines, words, chars, lengths bool) func() {
+	saved := []bool{countLines, countWords, countChars, maxLengths}
+	countLines, countWords, countChars, maxLengths = lines, words, chars, lengths
+	return func() {
+		countLines, countWords, countChars, maxLengths = saved[0], saved[1], saved[2], saved[3]
+	}
+}
+
+func TestCounter(t *testing.T) {
+	pieces := []string{"a", "word", " ", "\t", "\n", "\r", "\v", "\f", "\x01", "é", "e\u0301", "\u200d", "日本", " ", " ", "　", "\u0085", "\xe2\x80", "\xff", "\xc2", "🙂"}
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		var data []byte
+		for j := random.Intn(60); j > 0; j-- {
+			data = append(data, pieces[random.Intn(len(pieces))]...)
+		}
+		want := referenceCount(data)
+		for _, flags := range [][4]bool{{true, true, true, true}, {false, true, false, false}, {true, false, false, false}} {
+			restore := setCounts(flags[0], flags[1], flags[2], flags[3])
+			for _, size := range []int{1, 2, 3, 7, len(data) + 1} {
+				var c counter
+				for start := 0; start < len(data); start += size {
+					c.write(data[start:min(start+size, len(data))])
+				}
+				got := c.result()
+				if got.bytes != want.bytes || got.lines != want.lines ||
+					flags[1] && got.words != want.words ||
+					flags[2] && (got.chars != want.chars || got.maxLineLength != want.maxLineLength) {
+					t.Errorf("%q flags %v chunks of %d: want: %+v - got: %+v", data, flags, size, want, got)
+				}
+			}
+			restore()
+		}
+	}
+}
+
+func TestCountParallel(t *testing.T) {
+	pieces := []string{"a", "word", " ", "\t", "\t", "\n", "\r", "\f", "é", "e\u0301", "日本", " ", "　", "\xe2\x80", "\xff", "🙂"}
+	random := rand.New(rand.NewSource(2))
+	defer setCounts(true, true, true, true)()
+	for i := 0; i < 200; i++ {
+		var data []byte
+		for j := random.Intn(80); j > 0; j-- {
+			data = append(data, pieces[random.Intn(len(pieces))]...)
+		}
+		want := referenceCount(data)
+		for parts := 2; parts < 12; parts++ {
+			got, err := countParallel(bytes.NewReader(data), int64(len(data)), parts)
+			if err != nil || got != want {
+				t.Errorf("%q in %d parts: want: %+v - got: %+v %v", data, parts, want, got, err)
+			}
+		}
+	}
+}
+
+func BenchmarkCounter(b *testing.B) {
+	data := bytes.Repeat([]byte("The quick brown fox jumps over the lazy dog, né à côté.\n"), 1<<14)
+	defer setCounts(true, true, true, true)()
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		var c counter
+		c.write(data)
+		c.result()
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+var countCode bool
+
+// language has what is needed to tell the comments of a language from its code.
+// With chars, ' opens a character literal only when one character, plain or
+// escaped, and a ' follow, so that lifetimes and labels are code. With
+// docstrings, a multiline string starting a line is a comment, as cloc counts
+// the docstrings of Python.
+type language struct {
+	name        string
+	extensions  []string
+	filenames   []string
+	lineComment []string
+	blocks      [][2]string
+	quotes      []quote
+	chars       bool
+	docstrings  bool
+}
+
+// quote delimits a string literal, where comment markers are code
+type quote struct {
+	open, close        string
+	escapes, multiline bool
+}
+
+var (
+	cQuotes      = []quote{{`"`, `"`, true, false}, {`'`, `'`, true, false}}
+	cBlocks      = [][2]string{{"/*", "*/"}}
+	cComments    = []string{"//"}
+	hashComments = []string{"#"}
+	xmlBlocks    = [][2]string{{"<!--", "-->"}}
+)
+
+var languages = []*language{
+	{name: "Go", extensions: []string{".go"}, lineComment: cComments, blocks: cBlocks,
+		quotes: []quote{{`"`, `"`, true, false}, {`'`, `'`, true, false}, {"`", "`", false, true}}},
+	{name: "C", extensions: []string{".c", ".h"}, lineComment: cComments, blocks: cBlocks, quotes: cQuotes},
+	{name: "C++", extensions: []string{".cc", ".cpp", ".cxx", ".hh", ".hpp"}, lineComment: cComments, blocks: cBlocks, quotes: cQuotes},
+	{name: "C#", extensions: []string{".cs"}, lineComment: cComments, blocks: cBlocks, quotes: cQuotes},
+	{name: "Java", extensions: []string{".java"}, lineComment: cComments, blocks: cBlocks, quotes: cQuotes},
+	{name: "Kotlin", extensions: []string{".kt"}, lineComment: cComments, blocks: cBlocks, quotes: cQuotes},
+	{name: "Swift", extensions: []string{".swift"}, lineComment: cComments, blocks: cBlocks, quotes: cQuotes},
+	{name: "Rust", extensions: []string{".rs"}, lineComment: cComments, blocks: cBlocks,
+		quotes: []quote{{`"`, `"`, true, true}}, chars: true},
+	{name: "JavaScript", extensions: []string{".js", ".mjs", ".cjs", ".jsx"}, lineComment: cComments, blocks: cBlocks,
+		quotes: append([]quote{{"`", "`", true, true}}, cQuotes...)},
+	{name: "TypeScript", extensions: []string{".ts", ".tsx"}, lineComment: cComments, blocks: cBlocks,
+		quotes: append([]quote{{"`", "`", true, true}}, cQuotes...)},
+	{name: "CSS", extensions: []string{".css"}, blocks: cBlocks, quotes: cQuotes},
+	{name: "Python", extensions: []string{".py"}, lineComment: hashComments,
+		quotes: append([]quote{{`"""`, `"""`, true, true}, {`'''`, `'''`, true, true}}, cQuotes...), docstrings: true},
+	{name: "Ruby", extensions: []string{".rb"}, lineComment: hashComments, quotes: cQuotes},
+	{name: "Shell", extensions: []string{".sh", ".bash", ".zsh"}, lineComment: hashComments,
+		quotes: []quote{{`"`, `"`, true, true}, {`'`, `'`, false, true}}},
+	{name: "Makefile", extensions: []string{".mk"}, filenames: []string{"Makefile", "makefile", "GNUmakefile"}, lineComment: hashComments},
+	{name: "Dockerfile", filenames: []string{"Dockerfile"}, lineComment: hashComments},
+	{name: "YAML", extensions: []string{".yml", ".yaml"}, lineComment: hashComments, quotes: cQuotes},
+	{name: "TOML", extensions: []string{".toml"}, lineComment: hashComments, quotes: cQuotes},
+	{name: "JSON", extensions: []string{".json"}, quotes: []quote{{`"`, `"`, true, false}}},
+	{name: "SQL", extensions: []string{".sql"}, lineComment: []string{"--"}, blocks: cBlocks,
+		quotes: []quote{{`'`, `'`, false, true}}},
+	{name: "Lua", extensions: []string{".lua"}, lineComment: []string{"--"}, blocks: [][2]string{{"--[[", "]]"}},
+		quotes: cQuotes},
+	{name: "HTML", extensions: []string{".html", ".htm"}, blocks: xmlBlocks},
+	{name: "XML", extensions: []string{".xml"}, blocks: xmlBlocks},
+	{name: "Markdown", extensions: []string{".md"}, blocks: xmlBlocks},
+}
+
+// languageOf detects the language of a file by its name, or gives nil
+func languageOf(name string) *language {
+	base, ext := filepath.Base(name), filepath.Ext(name)
+	for _, lang := range languages {
+		for _, filename := range lang.filenames {
+			if base == filename {
+				return lang
+			}
+		}
+		for _, extension := range lang.extensions {
+			if ext == extension {
+				return lang
+			}
+		}
+	}
+	return nil
+}
+
+// codeCounter classifies the lines of a file as blank, comment or code, a line
+// with both comments and code being code. It keeps the comment block or string
+// in progress from a line to the next.
+type codeCounter struct {
+	lang     *language
+	line     []byte
+	blockEnd string
+	quote    *quote
+}
+
+func (c *codeCounter) write(data []byte, result *counters) {
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			c.line = append(c.line, data...)
+			return
+		}
+		c.line = append(c.line, data[:i]...)
+		c.classify(result)
+		data = data[i+1:]
+	}
+}
+
+func (c *codeCounter) finish(result *counters) {
+	if len(c.line) > 0 {
+		c.classify(result)
+	}
+}
+
+func (c *codeCounter) classify(result *counters) {
+	text := string(bytes.TrimSpace(c.line))
+	c.line = c.line[:0]
+	if text == "" {
+		if c.quote != nil {
+			result.code++
+		} else {
+			result.blank++
+		}
+		return
+	}
+
+	hasCode, hasComment := false, false
+	for i := 0; i < len(text); {
+		switch {
+		case c.blockEnd != "":
+			hasComment = true
+			if strings.HasPrefix(text[i:], c.blockEnd) {
+				i += len(c.blockEnd)
+				c.blockEnd = ""
+			} else {
+				i++
+			}
+		case c.quote != nil:
+			hasCode = true
+			if c.quote.escapes && text[i] == '\\' {
+				i += 2
+			} else if strings.HasPrefix(text[i:], c.quote.close) {
+				i += len(c.quote.close)
+				c.quote = nil
+			} else {
+				i++
+			}
+		case text[i] == ' ' || text[i] == '\t':
+			i++
+		default:
+			if i = c.start(text, i); i < 0 {
+				hasComment = true
+				i = len(text)
+			} else if c.blockEnd != "" {
+				hasComment = true
+			} else {
+				hasCode = true
+			}
+		}
+	}
+	if c.quote != nil && !c.quote.multiline {
+		c.quote = nil
+	}
+
+	switch {
+	case hasCode:
+		result.code++
+	case hasComment:
+		result.comment++
+	default:
+		result.blank++
+	}
+}
+
+// start looks for a comment or string starting at i, giving where to go on, or
+// -1 for a line comment
+func (c *codeCounter) start(text string, i int) int {
+	rest := text[i:]
+	for _, block := range c.lang.blocks {
+		if strings.HasPrefix(rest, block[0]) {
+			c.blockEnd = block[1]
+			return i + len(block[0])
+		}
+	}
+	for _, comment := range c.lang.lineComment {
+		if strings.HasPrefix(rest, comment) {
+			return -1
+		}
+	}
+	if c.lang.chars && rest[0] == '\'' {
+		return i + max(charLiteral(rest), 1)
+	}
+	for q := range c.lang.quotes {
+		if quote := &c.lang.quotes[q]; strings.HasPrefix(rest, quote.open) {
+			if c.lang.docstrings && quote.multiline && i == 0 {
+				c.blockEnd = quote.close
+			} else {
+				c.quote = quote
+			}
+			return i + len(quote.open)
+		}
+	}
+	return i + 1
+}
+
+// charLiteral gives the length of the character literal text starts with, or 0
+// if it is not one
+func charLiteral(text string) int {
+	if len(text) > 2 && text[1] == '\\' {
+		// the longest escape is \u{10FFFF}
+		if end := strings.IndexByte(text[3:], '\''); end >= 0 && end <= 8 {
+			return end + 4
+		}
+		return 0
+	}
+	_, size := utf8.DecodeRuneInString(text[1:])
+	if size > 0 && 1+size < len(text) && text[1+size] == '\'' {
+		return size + 2
+	}
+	return 0
+}
@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestCodeCounter(t *testing.T) {
+	testCases := []struct {
+		name, text           string
+		blank, comment, code int64
+	}{
+		{"a.go", "package main\n\n// comment\n/* block\n\nstill */\nx := 1 // trailing\n", 2, 3, 2},
+		{"a.go", "s := \"/* not a comment\"\nr := `raw\n// inside\n`\n/* a */ b()\n", 0, 0, 5},
+		{"a.c", "int a; /* starts\n * here */ int b;\nchar c = '\"'; // quote\n", 0, 0, 3},
+		{"a.py", "# comment\n\"\"\"doc\n# inside\n\"\"\"\nx = '#'\n", 0, 4, 1},
+		{"a.py", "def f():\n    '''doc'''\n    s = \"\"\"a\n# b\n\"\"\"\n", 0, 1, 4},
+		{"a.rs", "let c = '\"'; // quote\nlet d = '\\''; let s = \"/*\";\n", 0, 0, 2},
+		{"a.rs", "fn f<'a>(x: &'a str) -> &'a str { x }\n'outer: loop {}\nlet e = '\\u{1F600}'; /* c */\n// only\n", 0, 1, 3},
+		{"a.rs", "let r = 'é'; let s = \"\n// inside\n\";\n", 0, 0, 3},
+		{"a.sh", "#!/bin/sh\necho \"a\n# inside\"\n  # indented\n", 0, 2, 2},
+		{"a.json", "{\n  \"a\": \"//\"\n}", 0, 0, 3},
+		{"a.sql", "-- comment\nselect '--' from t; /* x */\n", 0, 1, 1},
+		{"a.lua", "--[[ block\n]] x = 1\n-- line\n", 0, 2, 1},
+		{"a.html", "<!-- a -->\n<p>text</p>\n\n", 1, 1, 1},
+	}
+	for _, c := range testCases {
+		var result counters
+		code := codeCounter{lang: languageOf(c.name)}
+		code.write([]byte(c.text), &result)
+		code.finish(&result)
+		if result.blank != c.blank || result.comment != c.comment || result.code != c.code {
+			t.Errorf("%s %q: want: %d %d %d - got: %d %d %d", c.name, c.text,
+				c.blank, c.comment, c.code, result.blank, result.comment, result.code)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// distinctMode is empty to not count the distinct words, exact to keep all of
+// them, or hll to estimate them with a HyperLogLog sketch of hllPrecision
+var distinctMode string
+var hllPrecision int
+
+// distinctWords counts the distinct words of a file, and of many files when
+// merged
+type distinctWords interface {
+	add(word string)
+	merge(other distinctWords)
+	// estimate gives the count and the bound of its standard error
+	estimate() (count, errorBound int64)
+}
+
+func newDistinctWords() distinctWords {
+	if distinctMode == "hll" {
+		return &hyperLogLog{precision: uint(hllPrecision), registers: make([]uint8, 1<<hllPrecision)}
+	}
+	return &exactWords{words: map[string]bool{}}
+}
+
+type exactWords struct {
+	words map[string]bool
+}
+
+func (e *exactWords) add(word string) {
+	e.words[word] = true
+}
+
+func (e *exactWords) merge(other distinctWords) {
+	for word := range other.(*exactWords).words {
+		e.words[word] = true
+	}
+}
+
+func (e *exactWords) estimate() (int64, int64) {
+	return int64(len(e.words)), 0
+}
+
+// hyperLogLog estimates the distinct words in 2^precision bytes, with a
+// standard error of 1.04/sqrt(2^precision)
+type hyperLogLog struct {
+	precision uint
+	registers []uint8
+}
+
+func (h *hyperLogLog) add(word string) {
+	hash := fnv.New64a()
+	hash.Write([]byte(word))
+	x := mix(hash.Sum64())
+	index := x >> (64 - h.precision)
+	rank := uint8(bits.LeadingZeros64(x<<h.precision|1<<(h.precision-1)) + 1)
+	h.registers[index] = max(h.registers[index], rank)
+}
+
+// mix spreads the bits of FNV, which are not random enough for the registers
+func mix(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+func (h *hyperLogLog) merge(other distinctWords) {
+	for i, rank := range other.(*hyperLogLog).registers {
+		h.registers[i] = max(h.registers[i], rank)
+	}
+}
+
+func (h *hyperLogLog) estimate() (int64, int64) {
+	m := float64(len(h.registers))
+	sum, zeros := 0.0, 0
+	for _, rank := range h.registers {
+		sum += math.Ldexp(1, -int(rank))
+		if rank == 0 {
+			zeros++
+		}
+	}
+	var alpha float64
+	switch len(h.registers) {
+	case 16:
+		alpha = 0.673
+	case 32:
+		alpha = 0.697
+	case 64:
+		alpha = 0.709
+	default:
+		alpha = 0.7213 / (1 + 1.079/m)
+	}
+	estimate := alpha * m * m / sum
+	// linear counting is more accurate for small counts
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return int64(math.Round(estimate)), int64(math.Ceil(estimate * 1.04 / math.Sqrt(m)))
+}
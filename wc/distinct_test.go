@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestDistinctWords(t *testing.T) {
+	defer func(mode string, precision int) {
+		distinctMode, hllPrecision = mode, precision
+	}(distinctMode, hllPrecision)
+
+	for _, mode := range []string{"exact", "hll"} {
+		for _, precision := range []int{10, 14} {
+			distinctMode, hllPrecision = mode, precision
+			for _, n := range []int{100, 5000, 200000} {
+				first, second := newDistinctWords(), newDistinctWords()
+				for i := 0; i < n; i++ {
+					word := fmt.Sprint("word", i)
+					first.add(word)
+					second.add(word)
+					if i%2 == 0 {
+						second.add(fmt.Sprint("other", i))
+					}
+				}
+				first.merge(second)
+				want := n + (n+1)/2
+				count, bound := first.estimate()
+				if math.Abs(float64(count-int64(want))) > 3*float64(bound) {
+					t.Errorf("%s p=%d: want: %d - got: %d ±%d", mode, precision, want, count, bound)
+				}
+				if mode == "exact" && bound != 0 {
+					t.Errorf("exact: bound %d", bound)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// outputFormat is text for the columns of wc, or json, csv or tsv for one
+// record per file, group or total with its kind, the selected counts and the
+// error, if any
+var outputFormat string
+
+var table *csv.Writer
+
+type column struct {
+	name     string
+	selected bool
+	count    int64
+}
+
+func columnsOf(result counters) []column {
+	var distinct, distinctError int64
+	if result.distinct != nil {
+		distinct, distinctError = result.distinct.estimate()
+	}
+	return []column{
+		{"lines", countLines, result.lines},
+		{"words", countWords, result.words},
+		{"chars", countChars, result.chars},
+		{"bytes", countBytes, result.bytes},
+		{"max_line_length", maxLengths, result.maxLineLength},
+		{"blank", countCode, result.blank},
+		{"comment", countCode, result.comment},
+		{"code", countCode, result.code},
+		{"distinct", distinctMode != "", distinct},
+		{"distinct_error", distinctMode == "hll", distinctError},
+	}
+}
+
+func startOutput() error {
+	switch outputFormat {
+	case "text", "json":
+		return nil
+	case "csv", "tsv":
+		table = csv.NewWriter(os.Stdout)
+		if outputFormat == "tsv" {
+			table.Comma = '\t'
+		}
+		header := []string{"name", "kind"}
+		for _, column := range columnsOf(counters{}) {
+			if column.selected {
+				header = append(header, column.name)
+			}
+		}
+		table.Write(append(header, "error"))
+		table.Flush()
+		return table.Error()
+	}
+	return fmt.Errorf("invalid argument %q for --format", outputFormat)
+}
+
+// printResult prints the counts of a file, or its error, in the output format.
+// The kind tells the records of files, groups and the total apart, as a file may
+// be named total.
+func printResult(result fileResult, name, kind string) {
+	if outputFormat == "text" {
+		if result.err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", result.err)
+		}
+		if result.opened {
+			printCounters(result.counters, name)
+		}
+		return
+	}
+	if name == "" {
+		name = "-"
+	}
+	errText := ""
+	if result.err != nil {
+		errText = result.err.Error()
+	}
+
+	if outputFormat == "json" {
+		fields := []string{`"name":` + jsonString(name), `"kind":` + jsonString(kind)}
+		if result.opened {
+			for _, column := range columnsOf(result.counters) {
+				if column.selected {
+					fields = append(fields, fmt.Sprintf("%q:%d", column.name, column.count))
+				}
+			}
+		}
+		if result.err != nil {
+			fields = append(fields, `"error":`+jsonString(errText))
+		}
+		fmt.Println("{" + strings.Join(fields, ",") + "}")
+		return
+	}
+
+	record := []string{name, kind}
+	for _, column := range columnsOf(result.counters) {
+		if !column.selected {
+			continue
+		}
+		if result.opened {
+			record = append(record, strconv.FormatInt(column.count, 10))
+		} else {
+			record = append(record, "")
+		}
+	}
+	table.Write(append(record, errText))
+	table.Flush()
+}
+
+func jsonString(s string) string {
+	encoded, _ := json.Marshal(s)
+	return string(encoded)
+}
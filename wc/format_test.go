@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+// captureOutput returns what f writes to the standard output
+func captureOutput(t *testing.T, f func()) string {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := os.Stdout
+	os.Stdout = writer
+	defer func() {
+		os.Stdout = saved
+	}()
+	f()
+	writer.Close()
+	output, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(output)
+}
+
+func TestPrintResult(t *testing.T) {
+	savedFormat, savedMode := outputFormat, distinctMode
+	savedCounts := []bool{countLines, countWords, countChars, countBytes, maxLengths, countCode}
+	defer func() {
+		outputFormat, distinctMode = savedFormat, savedMode
+		countLines, countWords, countChars, countBytes, maxLengths, countCode =
+			savedCounts[0], savedCounts[1], savedCounts[2], savedCounts[3], savedCounts[4], savedCounts[5]
+	}()
+	countLines, countWords, countChars, countBytes, maxLengths, countCode = true, false, true, true, true, false
+	distinctMode = ""
+
+	results := func() {
+		printResult(fileResult{counters{lines: 2, chars: 9, bytes: 10, maxLineLength: 4}, true, nil}, "total", "file")
+		printResult(fileResult{err: errors.New("missing: no such file")}, "missing", "file")
+		printResult(fileResult{counters{lines: 1}, true, errors.New("a, \"read\" error")}, "", "file")
+		printResult(fileResult{counters{lines: 3, chars: 9, bytes: 10}, true, nil}, "Go", "group")
+		printResult(fileResult{counters{lines: 3, chars: 9, bytes: 10, maxLineLength: 4}, true, nil}, "total", "total")
+	}
+	testCases := map[string]string{
+		"json": `{"name":"total","kind":"file","lines":2,"chars":9,"bytes":10,"max_line_length":4}` + "\n" +
+			`{"name":"missing","kind":"file","error":"missing: no such file"}` + "\n" +
+			`{"name":"-","kind":"file","lines":1,"chars":0,"bytes":0,"max_line_length":0,"error":"a, \"read\" error"}` + "\n" +
+			`{"name":"Go","kind":"group","lines":3,"chars":9,"bytes":10,"max_line_length":0}` + "\n" +
+			`{"name":"total","kind":"total","lines":3,"chars":9,"bytes":10,"max_line_length":4}` + "\n",
+		"csv": "name,kind,lines,chars,bytes,max_line_length,error\n" +
+			"total,file,2,9,10,4,\n" +
+			"missing,file,,,,,missing: no such file\n" +
+			"-,file,1,0,0,0,\"a, \"\"read\"\" error\"\n" +
+			"Go,group,3,9,10,0,\n" +
+			"total,total,3,9,10,4,\n",
+		"tsv": "name\tkind\tlines\tchars\tbytes\tmax_line_length\terror\n" +
+			"total\tfile\t2\t9\t10\t4\t\n" +
+			"missing\tfile\t\t\t\t\tmissing: no such file\n" +
+			"-\tfile\t1\t0\t0\t0\t\"a, \"\"read\"\" error\"\n" +
+			"Go\tgroup\t3\t9\t10\t0\t\n" +
+			"total\ttotal\t3\t9\t10\t4\t\n",
+	}
+	for format, want := range testCases {
+		outputFormat = format
+		got := captureOutput(t, func() {
+			if err := startOutput(); err != nil {
+				t.Fatal(err)
+			}
+			results()
+		})
+		if got != want {
+			t.Errorf("%s: want:\n%s\ngot:\n%s", format, want, got)
+		}
+	}
+
+	outputFormat = "xml"
+	if err := startOutput(); err == nil {
+		t.Errorf("xml: expected an error")
+	}
+}
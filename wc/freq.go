@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+var freqTop int
+var foldCase, stripPunct bool
+var stopwordsFile string
+
+var stopwords = map[string]bool{}
+
+// wordFreq has the counts of the words of all the files, merged as each one
+// is finished
+var wordFreq = map[string]int64{}
+var wordFreqMutex sync.Mutex
+
+// wordCollector counts each word of a file, split as for the word count, and
+// the distinct words, as the flags ask
+type wordCollector struct {
+	word     []byte
+	pending  []byte
+	counts   map[string]int64
+	distinct distinctWords
+}
+
+func (w *wordCollector) write(data []byte) {
+	if len(w.pending) > 0 {
+		data = append(w.pending, data...)
+		w.pending = nil
+	}
+	for i := 0; i < len(data); {
+		b := data[i]
+		if b < utf8.RuneSelf {
+			if b == ' ' || b >= '\t' && b <= '\r' {
+				w.endWord()
+			} else {
+				w.word = append(w.word, b)
+			}
+			i++
+			continue
+		}
+		if !utf8.FullRune(data[i:]) {
+			w.pending = append([]byte(nil), data[i:]...)
+			return
+		}
+		r, size := utf8.DecodeRune(data[i:])
+		if unicode.IsSpace(r) {
+			w.endWord()
+		} else {
+			w.word = append(w.word, data[i:i+size]...)
+		}
+		i += size
+	}
+}
+
+func (w *wordCollector) endWord() {
+	if len(w.word) == 0 {
+		return
+	}
+	if word := normalizeWord(string(w.word)); word != "" && !stopwords[word] {
+		if w.counts != nil {
+			w.counts[word]++
+		}
+		if w.distinct != nil {
+			w.distinct.add(word)
+		}
+	}
+	w.word = w.word[:0]
+}
+
+// flush counts the last word, taking an incomplete character as part of it
+func (w *wordCollector) flush() {
+	w.word = append(w.word, w.pending...)
+	w.pending = nil
+	w.endWord()
+}
+
+// finish counts the last word and adds the counts to wordFreq
+func (w *wordCollector) finish() {
+	w.flush()
+	if w.counts == nil {
+		return
+	}
+	wordFreqMutex.Lock()
+	defer wordFreqMutex.Unlock()
+	for word, count := range w.counts {
+		wordFreq[word] += count
+	}
+}
+
+// normalizeWord folds the case of a word and strips the punctuation around it,
+// as the flags say
+func normalizeWord(word string) string {
+	if foldCase {
+		word = strings.ToLower(word)
+	}
+	if stripPunct {
+		word = strings.TrimFunc(word, unicode.IsPunct)
+	}
+	return word
+}
+
+// loadStopwords reads the words to leave out of the frequencies, separated by
+// spaces or lines
+func loadStopwords(filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Split(bufio.ScanWords)
+	for scanner.Scan() {
+		if word := normalizeWord(scanner.Text()); word != "" {
+			stopwords[word] = true
+		}
+	}
+	return scanner.Err()
+}
+
+// printFrequencies prints the most frequent words, by count and then by word
+func printFrequencies() {
+	words := make([]string, 0, len(wordFreq))
+	for word := range wordFreq {
+		words = append(words, word)
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if wordFreq[words[i]] != wordFreq[words[j]] {
+			return wordFreq[words[i]] > wordFreq[words[j]]
+		}
+		return words[i] < words[j]
+	})
+	if len(words) > freqTop {
+		words = words[:freqTop]
+	}
+	if len(words) == 0 {
+		return
+	}
+	width := len(fmt.Sprint(wordFreq[words[0]]))
+	for _, word := range words {
+		fmt.Printf("%*d %s\n", width, wordFreq[word], word)
+	}
+}
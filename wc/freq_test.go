@@ -0,0 +1,36 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWordCollector(t *testing.T) {
+	text := "The cat, the dog and THE bird.\n\"Cat\"\tdog日本\xff é"
+	testCases := []struct {
+		fold, strip bool
+		stop        map[string]bool
+		want        map[string]int64
+	}{
+		{false, false, nil, map[string]int64{"The": 1, "cat,": 1, "the": 1, "dog": 1, "and": 1,
+			"THE": 1, "bird.": 1, `"Cat"`: 1, "dog日本\xff": 1, "é": 1}},
+		{true, true, map[string]bool{"and": true}, map[string]int64{"the": 3, "cat": 2, "dog": 1,
+			"bird": 1, "dog日本\ufffd": 1, "é": 1}},
+	}
+	defer func() {
+		foldCase, stripPunct, stopwords = false, false, map[string]bool{}
+	}()
+	for _, c := range testCases {
+		foldCase, stripPunct, stopwords = c.fold, c.strip, c.stop
+		for size := 1; size <= len(text); size *= 2 {
+			words := wordCollector{counts: map[string]int64{}}
+			for start := 0; start < len(text); start += size {
+				words.write([]byte(text[start:min(start+size, len(text))]))
+			}
+			words.flush()
+			if !reflect.DeepEqual(words.counts, c.want) {
+				t.Errorf("fold %t strip %t chunks of %d: want: %v - got: %v", c.fold, c.strip, size, c.want, words.counts)
+			}
+		}
+	}
+}
@@ -1,7 +1,8 @@
 #!/bin/bash
 set -e
+export LC_ALL=C.UTF-8
 
-go build -o ccwc.exe ccwc.go
+go build -o ccwc.exe .
 
 echo Testing -c option
 wc -c test.txt > wc.out
@@ -33,6 +34,11 @@ wc test.txt > wc.out
 ./ccwc.exe test.txt > ccwc.out
 diff --ignore-all-space wc.out ccwc.out
 
+echo Testing parallel
+wc -l -w -m -L test.txt > wc.out
+./ccwc.exe -j 4 -l -w -m -L test.txt > ccwc.out
+diff --ignore-all-space wc.out ccwc.out
+
 echo Testing multiple
 wc test.txt test.sh ccwc.go > wc.out
 ./ccwc.exe test.txt test.sh ccwc.go > ccwc.out
@@ -48,5 +54,39 @@ wc -c ccwc.exe > wc.out
 ./ccwc.exe -c ccwc.exe > ccwc.out
 diff --ignore-all-space wc.out ccwc.out
 
+echo Testing column width
+for options in "" "-l" "-c -L"; do
+    wc $options test.txt > wc.out
+    ./ccwc.exe $options test.txt > ccwc.out
+    diff wc.out ccwc.out
+    wc $options test.txt test.sh > wc.out
+    ./ccwc.exe $options test.txt test.sh > ccwc.out
+    diff wc.out ccwc.out
+    cat test.txt | wc $options > wc.out
+    cat test.txt | ./ccwc.exe $options > ccwc.out
+    diff wc.out ccwc.out
+done
+
+echo Testing --files0-from
+printf 'test.txt\0test.sh\0' > files0.txt
+wc --files0-from=files0.txt > wc.out
+./ccwc.exe --files0-from=files0.txt > ccwc.out
+diff wc.out ccwc.out
+cat files0.txt | wc --files0-from=- > wc.out
+cat files0.txt | ./ccwc.exe --files0-from=- > ccwc.out
+diff wc.out ccwc.out
+
+echo Testing --total
+wc test.txt test.sh | head -n 2 > wc.out
+./ccwc.exe --total=never test.txt test.sh > ccwc.out
+diff wc.out ccwc.out
+wc test.txt test.sh | tail -n 1 | sed 's/ total$//' > wc.out
+./ccwc.exe --total=only test.txt test.sh > ccwc.out
+diff wc.out ccwc.out
+wc test.txt > wc.out
+wc test.txt | sed 's/test.txt$/total/' >> wc.out
+./ccwc.exe --total=always test.txt > ccwc.out
+diff wc.out ccwc.out
+
 echo All tests passed
-rm ccwc.exe wc.out ccwc.out
\ No newline at end of file
+rm ccwc.exe wc.out ccwc.out files0.txt
\ No newline at end of file
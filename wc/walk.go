@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var recursive bool
+var includes, excludes patternList
+var ignoreFile, followLinks, groupBy string
+
+// patternList collects the patterns of a flag given many times
+type patternList []string
+
+func (p *patternList) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *patternList) Set(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("%q: %w", pattern, err)
+	}
+	*p = append(*p, pattern)
+	return nil
+}
+
+// matchAny tells if the name of a file, or its path from where the walk started
+// for the patterns with a slash, matches any of the patterns
+func (p patternList) matchAny(rel []string) bool {
+	for _, pattern := range p {
+		name := rel[len(rel)-1]
+		if strings.Contains(pattern, "/") {
+			name = strings.Join(rel, "/")
+		}
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoreRule is a line of an ignore file, with the syntax of .gitignore
+type ignoreRule struct {
+	base            []string
+	segments        []string
+	negate, dirOnly bool
+	anchored        bool
+}
+
+func readIgnoreFile(filename string, base []string) (rules []ignoreRule, err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		rule := ignoreRule{base: base}
+		if line[0] == '!' {
+			rule.negate = true
+			line = line[1:]
+		} else if line[0] == '\\' {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		// a slash at the start or in the middle makes the pattern relative to
+		// the directory of the file, otherwise it matches at any depth
+		rule.anchored = strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		if line == "" {
+			continue
+		}
+		rule.segments = strings.Split(line, "/")
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+func (rule ignoreRule) matches(rel []string, isDir bool) bool {
+	if rule.dirOnly && !isDir || len(rel) <= len(rule.base) {
+		return false
+	}
+	for i, segment := range rule.base {
+		if rel[i] != segment {
+			return false
+		}
+	}
+	rel = rel[len(rule.base):]
+	if !rule.anchored {
+		return matchSegments(rule.segments, rel[len(rel)-1:])
+	}
+	return matchSegments(rule.segments, rel)
+}
+
+// matchSegments matches a path by segments, ** matching any number of them
+func matchSegments(patterns, segments []string) bool {
+	if len(patterns) == 0 {
+		return len(segments) == 0
+	}
+	if patterns[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(patterns[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	matched, _ := path.Match(patterns[0], segments[0])
+	return matched && matchSegments(patterns[1:], segments[1:])
+}
+
+// ignored applies the rules in order, the last one matching deciding
+func ignored(rules []ignoreRule, rel []string, isDir bool) bool {
+	result := false
+	for _, rule := range rules {
+		if rule.matches(rel, isDir) {
+			result = !rule.negate
+		}
+	}
+	return result
+}
+
+// expandNames replaces the directories among names with the regular files in
+// them, in the order of a walk by name. The files found are filtered by
+// --include and --exclude, and by the ignore files of the directories walked,
+// which apply to their subtrees like .gitignore files do, skipping the .git
+// directories too. Symbolic links are followed as --follow says: never, only
+// for the names given (args) or always, stopping at loops. Names of files that
+// are not directories are kept, so that they are counted and their errors
+// reported in order.
+func expandNames(names []string) (expanded []string) {
+	for _, name := range names {
+		stat, err := os.Lstat(name)
+		if err == nil && stat.Mode()&os.ModeSymlink != 0 && followLinks != "never" {
+			stat, err = os.Stat(name)
+		}
+		if err != nil || !stat.IsDir() {
+			expanded = append(expanded, name)
+			continue
+		}
+		expanded = walkDir(expanded, name, nil, nil, []os.FileInfo{stat})
+	}
+	return
+}
+
+func walkDir(expanded []string, dir string, rel []string, rules []ignoreRule, ancestors []os.FileInfo) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return expanded
+	}
+	if ignoreFile != "" {
+		added, err := readIgnoreFile(filepath.Join(dir, ignoreFile), rel)
+		if err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+		rules = append(rules[:len(rules):len(rules)], added...)
+	}
+
+	for _, entry := range entries {
+		name := filepath.Join(dir, entry.Name())
+		entryRel := append(rel[:len(rel):len(rel)], entry.Name())
+		mode := entry.Type()
+		if mode&os.ModeSymlink != 0 {
+			if followLinks != "always" {
+				continue
+			}
+			stat, err := os.Stat(name)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				continue
+			}
+			mode = stat.Mode().Type()
+		}
+
+		switch {
+		case mode.IsDir():
+			if excludes.matchAny(entryRel) || ignored(rules, entryRel, true) ||
+				ignoreFile != "" && entry.Name() == ".git" {
+				continue
+			}
+			stat, err := os.Stat(name)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				continue
+			}
+			if sameDir(ancestors, stat) {
+				fmt.Fprintf(os.Stderr, "%s: skipping a loop of symbolic links\n", name)
+				continue
+			}
+			expanded = walkDir(expanded, name, entryRel, rules, append(ancestors, stat))
+		case mode.IsRegular():
+			if excludes.matchAny(entryRel) || len(includes) > 0 && !includes.matchAny(entryRel) ||
+				ignored(rules, entryRel, false) {
+				continue
+			}
+			expanded = append(expanded, name)
+		}
+	}
+	return expanded
+}
+
+func sameDir(ancestors []os.FileInfo, stat os.FileInfo) bool {
+	for _, ancestor := range ancestors {
+		if os.SameFile(ancestor, stat) {
+			return true
+		}
+	}
+	return false
+}
+
+// groupKey gives the group of a file for --group: its directory, its
+// extension or its language
+func groupKey(name string) string {
+	switch groupBy {
+	case "":
+		return ""
+	case "dir":
+		return filepath.Dir(name)
+	case "lang":
+		if lang := languageOf(name); lang != nil {
+			return lang.name
+		}
+	default:
+		// the dot starting the name of a hidden file doesn't start an extension
+		base := filepath.Base(name)
+		if ext := filepath.Ext(base); ext != "" && ext != base {
+			return ext
+		}
+	}
+	return "(none)"
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIgnoreRules(t *testing.T) {
+	dir := t.TempDir()
+	ignore := "# comment\n*.log\n!keep.log\nbuild/\n/root.txt\ndocs/**/*.md\n\\!bang\n"
+	if err := os.WriteFile(filepath.Join(dir, ".gitignore"), []byte(ignore), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := readIgnoreFile(filepath.Join(dir, ".gitignore"), []string{"sub"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"sub/a.log", false, true},
+		{"sub/x/y/a.log", false, true},
+		{"sub/keep.log", false, false},
+		{"other/a.log", false, false},
+		{"sub/build", true, true},
+		{"sub/build", false, false},
+		{"sub/root.txt", false, true},
+		{"sub/x/root.txt", false, false},
+		{"sub/docs/a.md", false, true},
+		{"sub/docs/x/y/a.md", false, true},
+		{"sub/x/docs/a.md", false, false},
+		{"sub/!bang", false, true},
+	}
+	for _, c := range testCases {
+		if got := ignored(rules, strings.Split(c.path, "/"), c.isDir); got != c.ignored {
+			t.Errorf("%s: want: %t - got: %t", c.path, c.ignored, got)
+		}
+	}
+}
+
+func TestWalkDir(t *testing.T) {
+	savedIncludes, savedExcludes, savedFollow, savedIgnore := includes, excludes, followLinks, ignoreFile
+	defer func() {
+		includes, excludes, followLinks, ignoreFile = savedIncludes, savedExcludes, savedFollow, savedIgnore
+	}()
+	ignoreFile = ""
+
+	dir := t.TempDir()
+	for _, name := range []string{".hidden.go", "a.go", "b.txt", "skip/e.go", "sub/c.go", "sub/d.log", "loop/f.go"} {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte("x\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for link, target := range map[string]string{"file.lnk": "a.go", "link": "sub", "loop/back": ".."} {
+		if err := os.Symlink(target, filepath.Join(dir, link)); err != nil {
+			t.Skipf("symbolic links: %v", err)
+		}
+	}
+
+	testCases := []struct {
+		names              []string
+		includes, excludes patternList
+		follow             string
+		want               []string
+	}{
+		{[]string{"."}, nil, nil, "never",
+			[]string{".hidden.go", "a.go", "b.txt", "loop/f.go", "skip/e.go", "sub/c.go", "sub/d.log"}},
+		{[]string{"."}, patternList{"*.go"}, patternList{"skip"}, "never",
+			[]string{".hidden.go", "a.go", "loop/f.go", "sub/c.go"}},
+		{[]string{"."}, nil, patternList{"sub/*.log", ".*", "loop"}, "never",
+			[]string{"a.go", "b.txt", "skip/e.go", "sub/c.go"}},
+		{[]string{"link", "file.lnk"}, nil, nil, "never", []string{"link", "file.lnk"}},
+		{[]string{"link", "file.lnk"}, nil, nil, "args", []string{"link/c.go", "link/d.log", "file.lnk"}},
+		{[]string{"."}, nil, nil, "args",
+			[]string{".hidden.go", "a.go", "b.txt", "loop/f.go", "skip/e.go", "sub/c.go", "sub/d.log"}},
+		// loop/back leads to the directory walked, and is skipped
+		{[]string{"."}, nil, patternList{"skip"}, "always",
+			[]string{".hidden.go", "a.go", "b.txt", "file.lnk", "link/c.go", "link/d.log", "loop/f.go", "sub/c.go", "sub/d.log"}},
+		{[]string{"loop"}, patternList{"*.go"}, nil, "always",
+			[]string{"loop/back/.hidden.go", "loop/back/a.go", "loop/back/link/c.go",
+				"loop/back/skip/e.go", "loop/back/sub/c.go", "loop/f.go"}},
+	}
+	// the loops skipped are reported on the standard error
+	stderr, err := os.CreateTemp(t.TempDir(), "stderr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	savedStderr := os.Stderr
+	os.Stderr = stderr
+	defer func() {
+		os.Stderr = savedStderr
+	}()
+	for _, c := range testCases {
+		includes, excludes, followLinks = c.includes, c.excludes, c.follow
+		var names []string
+		for _, name := range c.names {
+			names = append(names, filepath.Join(dir, name))
+		}
+		var got []string
+		for _, name := range expandNames(names) {
+			rel, err := filepath.Rel(dir, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, filepath.ToSlash(rel))
+		}
+		if strings.Join(got, " ") != strings.Join(c.want, " ") {
+			t.Errorf("%v %v %v %s:\nwant: %v\ngot:  %v", c.names, c.includes, c.excludes, c.follow, c.want, got)
+		}
+	}
+	os.Stderr = savedStderr
+	stderr.Close()
+	output, err := os.ReadFile(stderr.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	loops := []string{filepath.Join(dir, "loop", "back"), filepath.Join(dir, "loop", "back", "loop")}
+	var want strings.Builder
+	for _, loop := range loops {
+		want.WriteString(loop + ": skipping a loop of symbolic links\n")
+	}
+	if string(output) != want.String() {
+		t.Errorf("want:\n%s\ngot:\n%s", want.String(), output)
+	}
+}
+
+func TestGroupKey(t *testing.T) {
+	saved := groupBy
+	defer func() {
+		groupBy = saved
+	}()
+	groupBy = "ext"
+	testCases := map[string]string{
+		"a/b.go":         ".go",
+		"a.tar.gz":       ".gz",
+		".gitignore":     "(none)",
+		"dir/.env":       "(none)",
+		".eslintrc.json": ".json",
+		"Makefile":       "(none)",
+		"dir.d/README":   "(none)",
+	}
+	for name, want := range testCases {
+		if got := groupKey(filepath.FromSlash(name)); got != want {
+			t.Errorf("%s: want: %s - got: %s", name, want, got)
+		}
+	}
+}
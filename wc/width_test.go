@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+func TestLineWidth(t *testing.T) {
+	testCases := []struct {
+		text  string
+		width int64
+	}{
+		{"plain ascii", 11},
+		{"日本語のテキスト", 16},
+		{"한국어", 6},
+		{"ＡＢＣ full", 11},
+		{"mixed 中文 and ελληνικά", 23},
+		{"e\u0301te\u0301", 3},
+		{"क्ष", 2},
+		{"a\u200bb\u200cc\u2060d\ufeff", 4},
+		{"👩\u200d💻", 4},
+		{"😀 🇯🇵", 5},
+		{"\u1100\u1161\u11a8", 2},
+		{"a\x01b\x7fc\u0085d", 4},
+		{"a\xffb\xe2\x80", 2},
+		{"ab\vcd", 4},
+		{"abcd\rxy", 4},
+		{"abcd\fxy\n\u00ad", 4},
+		{"\t日本\tx", 17},
+	}
+	defer setCounts(false, false, false, true)()
+	for _, c := range testCases {
+		var counter counter
+		counter.write([]byte(c.text))
+		if got := counter.result().maxLineLength; got != c.width {
+			t.Errorf("%q: want: %d - got: %d", c.text, c.width, got)
+		}
+	}
+}